@@ -296,10 +296,12 @@ function renderInventory() {
             const item = inventory[i];
             slot.classList.add('filled');
             slot.textContent = item.Name ? item.Name.substring(0, 4) : '???';
+            if (item.Count > 1) slot.textContent += ` x${item.Count}`;
             slot.title = getItemTooltip(item);
             
             if (item.Type === 1) slot.style.color = 'cyan';
             if (item.Type === 2) slot.style.color = 'violet';
+            if (item.Type === 3) slot.style.color = 'lightgreen';
 
             slot.onclick = () => {
                 if (sellMode) {
@@ -322,6 +324,11 @@ function renderInventory() {
                              alert("Invalid price");
                          }
                      }
+                } else if (item.Type === 3) {
+                    ws.send(JSON.stringify({
+                        type: "USE_ITEM",
+                        item_id: item.ID
+                    }));
                 } else {
                     let targetSlot = -1;
                     for (let s = 0; s < 5; s++) {
@@ -395,6 +402,11 @@ const monsters = new Map();
 const projectiles = new Map();
 const npcs = new Map();
 let portals = [];
+let walls = [];
+let damageTexts = [];
+let skills = [];
+
+const PROTOCOL_VERSION = 2;
 
 let myId = null;
 let myStats = {};
@@ -414,6 +426,19 @@ function connect() {
         console.log('Connected');
         statusEl.textContent = 'Connected';
         statusEl.style.color = '#0f0';
+
+        ws.send(JSON.stringify({
+            type: 'HELLO',
+            version: PROTOCOL_VERSION,
+            codecs: ['json'],
+            capabilities: ['resume', 'compression']
+        }));
+
+        // Pick up our character again if the last connection dropped
+        const token = sessionStorage.getItem('resumeToken');
+        if (token) {
+            ws.send(JSON.stringify({ type: 'RESUME', token }));
+        }
     };
 
     ws.onclose = () => {
@@ -442,9 +467,10 @@ function handleMessage(msg) {
                 msg.players.forEach(p => {
                     seenPlayers.add(p.id);
                     if (!players.has(p.id)) {
-                        players.set(p.id, { x: p.x, y: p.y, color: getRandomColor() });
+                        players.set(p.id, { x: p.x, y: p.y, color: getRandomColor(), away: p.away });
                     } else {
                         const existing = players.get(p.id);
+                        existing.away = p.away;
                         if (p.id !== myId) {
                             existing.x = p.x;
                             existing.y = p.y;
@@ -490,21 +516,7 @@ function handleMessage(msg) {
             }
             for (const [id] of projectiles) { if (!seenProjs.has(id)) projectiles.delete(id); }
 
-            // NPCs
-            const seenNPCs = new Set();
-            if (msg.npcs) {
-                msg.npcs.forEach(n => {
-                    seenNPCs.add(n.id);
-                    if (!npcs.has(n.id)) {
-                        npcs.set(n.id, { x: n.x, y: n.y, type: n.type });
-                    } else {
-                        const existing = npcs.get(n.id);
-                        existing.x = n.x;
-                        existing.y = n.y;
-                    }
-                });
-            }
-            for (const [id] of npcs) { if (!seenNPCs.has(id)) npcs.delete(id); }
+            // NPCs arrive with MAP_SWITCH since protocol version 2
             break;
 
         case 'INVENTORY':
@@ -525,7 +537,10 @@ function handleMessage(msg) {
                 atk: msg.attack,
                 def: msg.defense,
                 speed: msg.speed,
-                gold: msg.gold
+                gold: msg.gold,
+                mana: msg.mana,
+                maxMana: msg.max_mana,
+                autoAttack: msg.auto_attack
             };
             updateStatsUI();
             break;
@@ -553,13 +568,17 @@ function handleMessage(msg) {
         case 'WELCOME':
             myId = msg.id;
             myIdEl.textContent = myId;
+            if (msg.token) sessionStorage.setItem('resumeToken', msg.token);
             myStats = {
                 hp: msg.hp,
                 maxHp: msg.max_hp,
                 atk: msg.attack,
                 def: msg.defense,
                 speed: msg.speed,
-                gold: msg.gold
+                gold: msg.gold,
+                mana: msg.mana,
+                maxMana: msg.max_mana,
+                autoAttack: msg.auto_attack
             };
             updateStatsUI();
             break;
@@ -568,12 +587,50 @@ function handleMessage(msg) {
             players.delete(msg.id);
             break;
 
+        case 'HELLO_REJECTED':
+            statusEl.textContent = `Client out of date: ${msg.reason}`;
+            statusEl.style.color = '#f00';
+            ws.onclose = null; // reconnecting won't help
+            break;
+
+        case 'RESUME_FAILED':
+            // Our old character is gone; the WELCOME before this was for a new one
+            console.log('Could not resume previous session');
+            break;
+
+        case 'SERVER_SHUTDOWN':
+            statusEl.textContent = `Server shutting down in ${msg.seconds}s`;
+            statusEl.style.color = '#fa0';
+            break;
+
+        case 'SKILLS':
+            skills = msg.skills || [];
+            break;
+
+        case 'DAMAGE':
+            (msg.events || []).forEach(ev => {
+                let color = ev.target === 'player' ? '#f44' : '#fff';
+                if (ev.effectiveness > 1) color = '#ff0';
+                else if (ev.effectiveness < 1) color = '#999';
+                damageTexts.push({
+                    x: ev.x,
+                    y: ev.y - 20,
+                    text: ev.crit ? `${ev.amount}!` : `${ev.amount}`,
+                    color: color,
+                    crit: ev.crit,
+                    born: performance.now()
+                });
+            });
+            break;
+
         case 'MAP_SWITCH':
             items.clear();
             monsters.clear();
             projectiles.clear();
             npcs.clear();
+            (msg.npcs || []).forEach(n => npcs.set(n.id, { x: n.x, y: n.y, type: n.type }));
             portals = msg.portals || [];
+            walls = msg.walls || [];
             
             players.forEach((_, id) => {
                 if (id !== myId) players.delete(id);
@@ -585,13 +642,13 @@ function handleMessage(msg) {
                 me.y = msg.y;
             }
             
-            mapNameEl.textContent = `Map: ${msg.map}`;
+            mapNameEl.textContent = `Map: ${msg.map.split('@')[0]}` + (msg.channel > 1 ? ` (ch. ${msg.channel})` : '');
             break;
     }
 }
 
 function updateStatsUI() {
-    statsEl.textContent = `HP: ${myStats.hp}/${myStats.maxHp} | ATK: ${myStats.atk} | DEF: ${myStats.def} | SPD: ${myStats.speed} | GOLD: ${myStats.gold}`;
+    statsEl.textContent = `HP: ${myStats.hp}/${myStats.maxHp} | MP: ${myStats.mana}/${myStats.maxMana} | ATK: ${myStats.atk} | DEF: ${myStats.def} | SPD: ${myStats.speed} | GOLD: ${myStats.gold}`;
 }
 
 const keys = {};
@@ -635,8 +692,45 @@ window.addEventListener('mousedown', (e) => {
     });
 });
 
+function nearestMonster() {
+    const me = players.get(myId);
+    if (!me) return null;
+    let best = null;
+    let bestDist = Infinity;
+    monsters.forEach((m, id) => {
+        const d = (m.x - me.x) ** 2 + (m.y - me.y) ** 2;
+        if (d < bestDist) {
+            bestDist = d;
+            best = { id: id, x: m.x, y: m.y };
+        }
+    });
+    return best;
+}
+
+function castSkill(index) {
+    const skill = skills[index];
+    const me = players.get(myId);
+    if (!skill || !me || !ws || ws.readyState !== WebSocket.OPEN) return;
+
+    const target = nearestMonster();
+    ws.send(JSON.stringify({
+        type: 'CAST',
+        skill_id: skill.id,
+        target_id: target ? target.id : 0,
+        dir_x: target ? target.x - me.x : 0,
+        dir_y: target ? target.y - me.y : 0
+    }));
+}
+
 window.addEventListener('keydown', (e) => {
     keys[e.key] = true;
+
+    if (e.repeat) return;
+    if (e.key >= '1' && e.key <= '9') {
+        castSkill(parseInt(e.key) - 1);
+    } else if (e.key === 'q' && ws && ws.readyState === WebSocket.OPEN) {
+        ws.send(JSON.stringify({ type: 'AUTO_ATTACK', enabled: !myStats.autoAttack }));
+    }
 });
 
 window.addEventListener('keyup', (e) => {
@@ -722,7 +816,7 @@ function update() {
         let moved = false;
         let newX = me.x;
         let newY = me.y;
-        const speed = myStats.speed || 5;
+        const speed = myStats.speed ?? 5;
 
         if (keys['ArrowUp'] || keys['w']) { newY -= speed; moved = true; }
         if (keys['ArrowDown'] || keys['s']) { newY += speed; moved = true; }
@@ -740,6 +834,12 @@ function update() {
         if (newX > canvas.width) newX = canvas.width;
         if (newY > canvas.height) newY = canvas.height;
 
+        if (blocked(me.x, me.y, newX, newY)) {
+            if (!blocked(me.x, me.y, newX, me.y)) newY = me.y;
+            else if (!blocked(me.x, me.y, me.x, newY)) newX = me.x;
+            else moved = false;
+        }
+
         if (moved) {
             me.x = newX;
             me.y = newY;
@@ -750,10 +850,25 @@ function update() {
     }
 }
 
+// Mirrors the server's tile check so movement into walls is not predicted
+function blocked(x0, y0, x1, y1) {
+    const steps = Math.ceil(Math.hypot(x1 - x0, y1 - y0) / 10);
+    for (let i = 0; i <= steps; i++) {
+        const t = steps > 0 ? i / steps : 1;
+        const cx = (Math.floor((x0 + (x1 - x0) * t) / 20) + 0.5) * 20;
+        const cy = (Math.floor((y0 + (y1 - y0) * t) / 20) + 0.5) * 20;
+        if (walls.some(w => cx >= w.x && cx < w.x + w.w && cy >= w.y && cy < w.y + w.h)) return true;
+    }
+    return false;
+}
+
 function draw() {
     ctx.fillStyle = '#1a1a1a';
     ctx.fillRect(0, 0, canvas.width, canvas.height);
 
+    ctx.fillStyle = '#555';
+    walls.forEach((w) => ctx.fillRect(w.x, w.y, w.w, w.h));
+
     items.forEach((item) => {
         if (item.type === 0) ctx.fillStyle = '#ffd700';
         else if (item.type === 1) ctx.fillStyle = 'cyan';
@@ -836,16 +951,31 @@ function draw() {
     // Players (Circles)
     players.forEach((p, id) => {
         ctx.fillStyle = id === myId ? '#ff0' : (p.color || '#fff');
+        ctx.globalAlpha = p.away ? 0.4 : 1;
 
         ctx.beginPath();
         ctx.arc(p.x, p.y, 10, 0, Math.PI * 2);
         ctx.fill();
+        ctx.globalAlpha = 1;
 
         ctx.fillStyle = '#fff';
         ctx.font = '10px Arial';
         ctx.textAlign = 'center';
         ctx.fillText(`P${id}`, p.x, p.y - 15);
     });
+
+    // Floating damage numbers
+    const now = performance.now();
+    damageTexts = damageTexts.filter(d => now - d.born < 800);
+    damageTexts.forEach((d) => {
+        const age = (now - d.born) / 800;
+        ctx.globalAlpha = 1 - age;
+        ctx.fillStyle = d.color;
+        ctx.font = d.crit ? 'bold 16px Arial' : '12px Arial';
+        ctx.textAlign = 'center';
+        ctx.fillText(d.text, d.x, d.y - age * 30);
+    });
+    ctx.globalAlpha = 1;
 }
 
 function loop() {
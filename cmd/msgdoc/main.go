@@ -0,0 +1,28 @@
+// Command msgdoc writes the list of client messages accepted by the server,
+// generated from the handler registry.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"log"
+	"mmorpg/internal/network"
+	"os"
+)
+
+func main() {
+	out := flag.String("o", "", "output file, stdout if empty")
+	flag.Parse()
+
+	var buf bytes.Buffer
+	if err := network.Handlers.WriteMarkdown(&buf); err != nil {
+		log.Fatal(err)
+	}
+	if *out == "" {
+		os.Stdout.Write(buf.Bytes())
+		return
+	}
+	if err := os.WriteFile(*out, buf.Bytes(), 0644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -1,23 +1,122 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"log"
+	"mmorpg/internal/config"
 	"mmorpg/internal/game"
 	"mmorpg/internal/network"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
 func main() {
-	g := game.NewGame()
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		// Usage was printed by the flag set
+		os.Exit(0)
+	}
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
+	g := game.NewGame(cfg.Game)
+	if err := g.LoadState(cfg.StatePath); err != nil {
+		log.Printf("Could not load state: %v", err)
+	}
 	go g.Start()
 
-	wsServer := network.NewWSServer(g)
+	hub := network.NewHub(g, network.SessionConfig{
+		PingInterval: time.Duration(cfg.PingSeconds) * time.Second,
+		ReadTimeout:  time.Duration(cfg.ReadTimeoutSeconds) * time.Second,
+		WriteTimeout: time.Duration(cfg.WriteTimeoutSeconds) * time.Second,
+		AFKTimeout:   time.Duration(cfg.AFKSeconds) * time.Second,
+		SendQueue:    cfg.SendQueue,
+
+		MaxFrameSize: cfg.MaxFrameBytes,
+		RateLimits:   cfg.RateLimits,
+		KickAfter:    cfg.KickAfterViolations,
+		BanAfter:     cfg.BanAfterKicks,
+		BanDuration:  time.Duration(cfg.BanMinutes) * time.Minute,
+	})
+
+	wsServer := network.NewWSServer(hub, network.WSConfig{
+		Path:           cfg.WSPath,
+		ClientDir:      cfg.ClientDir,
+		AllowedOrigins: cfg.AllowedOrigins,
+		Compression:    cfg.Compression,
+	})
+
+	host, _, _ := net.SplitHostPort(cfg.Listen)
+	tlsConfig, err := network.TLSConfig(cfg.TLSCert, cfg.TLSKey, cfg.TLSSelfSigned, []string{host})
+	if err != nil {
+		log.Fatalf("TLS: %v", err)
+	}
+	if cfg.TLSSelfSigned {
+		log.Println("Using a self-signed certificate; browsers will warn until it is trusted")
+	}
+
+	srv := &http.Server{Addr: cfg.Listen, Handler: wsServer.Handler(), TLSConfig: tlsConfig}
 
-	http.Handle("/", http.FileServer(http.Dir("client")))
-	http.HandleFunc("/ws", wsServer.HandleWS)
+	go func() {
+		var err error
+		if tlsConfig != nil {
+			log.Printf("Server starting on %s with TLS (WebSocket at %s)", cfg.Listen, cfg.WSPath)
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			log.Printf("Server starting on %s (WebSocket at %s)", cfg.Listen, cfg.WSPath)
+			err = srv.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
 
-	log.Println("Server starting on 0.0.0.0:9000 (Accessible from external IPs, e.g., 192.168.0.3:9000)")
-	if err := http.ListenAndServe("0.0.0.0:9000", nil); err != nil {
-		log.Fatal(err)
+	var tcpServer *network.Server
+	if cfg.TCPListen != "" {
+		tcpServer = network.NewServer(cfg.TCPListen, hub)
+		tcpServer.TLSConfig = tlsConfig
+		go func() {
+			if err := tcpServer.Start(); err != nil {
+				log.Fatal(err)
+			}
+		}()
+	}
+
+	sigch := make(chan os.Signal, 1)
+	signal.Notify(sigch, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-sigch
+	log.Printf("Received %v, shutting down", sig)
+
+	// Stop accepting new connections; established WebSockets are hijacked
+	// and stay open until closed below.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("HTTP shutdown: %v", err)
+	}
+	cancel()
+	if tcpServer != nil {
+		tcpServer.StopAccepting()
+	}
+
+	for i := cfg.ShutdownSeconds; i > 0; i-- {
+		g.AnnounceShutdown(i)
+		time.Sleep(time.Second)
+	}
+
+	g.Stop()
+	if err := g.SaveState(cfg.StatePath); err != nil {
+		log.Printf("Could not save state: %v", err)
+	}
+	log.Printf("Closed %d sessions", hub.Shutdown())
+	if tcpServer != nil {
+		tcpServer.Stop()
 	}
+	log.Println("Server stopped")
 }
@@ -0,0 +1,241 @@
+// Package config loads server settings from a JSON file, environment
+// variables and command-line flags, in increasing order of precedence.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"mmorpg/internal/game"
+	"mmorpg/internal/network"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type Config struct {
+	Listen    string `json:"listen"`     // HTTP listen address
+	TCPListen string `json:"tcp_listen"` // raw TCP listen address, empty to disable
+	ClientDir string `json:"client_dir"` // static files served at /
+	WSPath    string `json:"ws_path"`    // WebSocket route
+	StatePath string `json:"state_path"` // where state is saved on shutdown
+
+	// TLS for both listeners: a certificate and key file, or a generated
+	// self-signed certificate for local testing
+	TLSCert       string `json:"tls_cert"`
+	TLSKey        string `json:"tls_key"`
+	TLSSelfSigned bool   `json:"tls_self_signed"`
+	// AllowedOrigins lists the web origins allowed to open WebSockets, "*"
+	// for any. Empty allows only pages served by this server.
+	AllowedOrigins []string `json:"allowed_origins"`
+	// Compression negotiates permessage-deflate on WebSockets
+	Compression bool `json:"compression"`
+
+	// ShutdownSeconds is how long players are warned before the server stops
+	ShutdownSeconds int `json:"shutdown_seconds"`
+	// Connection timings in seconds, 0 disables the check. Sessions are
+	// pinged every PingSeconds and dropped after ReadTimeoutSeconds without
+	// frames or pongs, or AFKSeconds without commands.
+	PingSeconds         int `json:"ping_seconds"`
+	ReadTimeoutSeconds  int `json:"read_timeout_seconds"`
+	WriteTimeoutSeconds int `json:"write_timeout_seconds"`
+	AFKSeconds          int `json:"afk_seconds"`
+	// SendQueue is how many outgoing messages are buffered per session
+	SendQueue int `json:"send_queue"`
+
+	// Flood protection. Entries in rate_limits replace the defaults of the
+	// same message type; "default" covers types without their own entry.
+	MaxFrameBytes       int                          `json:"max_frame_bytes"`
+	RateLimits          map[string]network.RateLimit `json:"rate_limits"`
+	KickAfterViolations int                          `json:"kick_after_violations"`
+	BanAfterKicks       int                          `json:"ban_after_kicks"`
+	BanMinutes          int                          `json:"ban_minutes"`
+
+	Game game.Config `json:"game"`
+}
+
+func Default() Config {
+	return Config{
+		Listen:          "0.0.0.0:9000",
+		ClientDir:       "client",
+		WSPath:          "/ws",
+		StatePath:       "data/state.json",
+		ShutdownSeconds: 5,
+		Compression:     true,
+
+		PingSeconds:         15,
+		ReadTimeoutSeconds:  45,
+		WriteTimeoutSeconds: 10,
+		AFKSeconds:          15 * 60,
+		SendQueue:           256,
+
+		MaxFrameBytes:       4096,
+		RateLimits:          network.DefaultRateLimits(),
+		KickAfterViolations: 50,
+		BanAfterKicks:       3,
+		BanMinutes:          10,
+
+		Game: game.DefaultConfig(),
+	}
+}
+
+// Load builds the configuration from defaults, the file named by -config or
+// MMORPG_CONFIG, MMORPG_* environment variables and finally flags in args,
+// then validates it.
+func Load(args []string) (Config, error) {
+	cfg := Default()
+
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	path := fs.String("config", os.Getenv("MMORPG_CONFIG"), "path to a JSON config file")
+	listen := fs.String("listen", "", "HTTP listen address")
+	tcpListen := fs.String("tcp-listen", "", "raw TCP listen address, empty to disable")
+	clientDir := fs.String("client-dir", "", "directory of the web client")
+	wsPath := fs.String("ws-path", "", "WebSocket route")
+	statePath := fs.String("state", "", "state file written on shutdown")
+	tlsCert := fs.String("tls-cert", "", "TLS certificate file")
+	tlsKey := fs.String("tls-key", "", "TLS key file")
+	tlsSelfSigned := fs.Bool("tls-self-signed", false, "serve TLS with a generated self-signed certificate")
+	tickRate := fs.Int("tick-rate", 0, "game ticks per second")
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+
+	if *path != "" {
+		if err := cfg.loadFile(*path); err != nil {
+			return cfg, err
+		}
+	}
+	if err := cfg.loadEnv(); err != nil {
+		return cfg, err
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "listen":
+			cfg.Listen = *listen
+		case "tcp-listen":
+			cfg.TCPListen = *tcpListen
+		case "client-dir":
+			cfg.ClientDir = *clientDir
+		case "ws-path":
+			cfg.WSPath = *wsPath
+		case "state":
+			cfg.StatePath = *statePath
+		case "tls-cert":
+			cfg.TLSCert = *tlsCert
+		case "tls-key":
+			cfg.TLSKey = *tlsKey
+		case "tls-self-signed":
+			cfg.TLSSelfSigned = *tlsSelfSigned
+		case "tick-rate":
+			cfg.Game.TickRate = *tickRate
+		}
+	})
+
+	return cfg, cfg.Validate()
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Config) loadEnv() error {
+	for name, dst := range map[string]*string{
+		"MMORPG_LISTEN":     &c.Listen,
+		"MMORPG_TCP_LISTEN": &c.TCPListen,
+		"MMORPG_CLIENT_DIR": &c.ClientDir,
+		"MMORPG_WS_PATH":    &c.WSPath,
+		"MMORPG_STATE_PATH": &c.StatePath,
+		"MMORPG_TLS_CERT":   &c.TLSCert,
+		"MMORPG_TLS_KEY":    &c.TLSKey,
+	} {
+		if v, ok := os.LookupEnv(name); ok {
+			*dst = v
+		}
+	}
+
+	if v, ok := os.LookupEnv("MMORPG_TICK_RATE"); ok {
+		rate, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("MMORPG_TICK_RATE: %w", err)
+		}
+		c.Game.TickRate = rate
+	}
+	return nil
+}
+
+func (c Config) Validate() error {
+	if _, _, err := net.SplitHostPort(c.Listen); err != nil {
+		return fmt.Errorf("listen: %w", err)
+	}
+	if c.TCPListen != "" {
+		if _, _, err := net.SplitHostPort(c.TCPListen); err != nil {
+			return fmt.Errorf("tcp_listen: %w", err)
+		}
+	}
+	if !strings.HasPrefix(c.WSPath, "/") {
+		return fmt.Errorf("ws_path must start with /, got %q", c.WSPath)
+	}
+	if (c.TLSCert == "") != (c.TLSKey == "") {
+		return errors.New("tls_cert and tls_key must be set together")
+	}
+	if c.TLSSelfSigned && c.TLSCert != "" {
+		return errors.New("tls_self_signed can't be combined with tls_cert")
+	}
+	for _, origin := range c.AllowedOrigins {
+		if origin == "*" {
+			continue
+		}
+		if u, err := url.Parse(origin); err != nil || u.Scheme == "" || u.Host == "" || u.Path != "" {
+			return fmt.Errorf("allowed_origins: %q is not an origin like https://example.com", origin)
+		}
+	}
+	if c.StatePath == "" {
+		return errors.New("state_path must not be empty")
+	}
+	if c.ShutdownSeconds < 0 {
+		return fmt.Errorf("shutdown_seconds must not be negative, got %d", c.ShutdownSeconds)
+	}
+	for name, v := range map[string]int{
+		"ping_seconds":          c.PingSeconds,
+		"read_timeout_seconds":  c.ReadTimeoutSeconds,
+		"write_timeout_seconds": c.WriteTimeoutSeconds,
+		"afk_seconds":           c.AFKSeconds,
+		"max_frame_bytes":       c.MaxFrameBytes,
+		"kick_after_violations": c.KickAfterViolations,
+		"ban_after_kicks":       c.BanAfterKicks,
+		"ban_minutes":           c.BanMinutes,
+	} {
+		if v < 0 {
+			return fmt.Errorf("%s must not be negative, got %d", name, v)
+		}
+	}
+	if c.ReadTimeoutSeconds > 0 && c.PingSeconds >= c.ReadTimeoutSeconds {
+		return fmt.Errorf("ping_seconds (%d) must be shorter than read_timeout_seconds (%d)", c.PingSeconds, c.ReadTimeoutSeconds)
+	}
+	if c.ReadTimeoutSeconds > 0 && c.PingSeconds == 0 {
+		return errors.New("read_timeout_seconds needs ping_seconds, or idle clients are dropped")
+	}
+	for typ, l := range c.RateLimits {
+		if l.Rate <= 0 || l.Burst < 1 {
+			return fmt.Errorf("rate_limits[%s] needs a positive rate and burst", typ)
+		}
+	}
+	if c.SendQueue <= 0 {
+		return fmt.Errorf("send_queue must be positive, got %d", c.SendQueue)
+	}
+	if info, err := os.Stat(c.ClientDir); err != nil || !info.IsDir() {
+		return fmt.Errorf("client_dir %q is not a directory", c.ClientDir)
+	}
+	return c.Game.Validate()
+}
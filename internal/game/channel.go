@@ -0,0 +1,111 @@
+package game
+
+import (
+	"fmt"
+	"time"
+)
+
+// channelEmptyTimeout is how long an extra channel stays open with nobody on
+// it. Channel 1, the base map, is never closed.
+const channelEmptyTimeout = time.Minute
+
+// root is the base map of a channel, or m itself
+func (m *WorldMap) root() *WorldMap {
+	if m.Base != nil {
+		return m.Base
+	}
+	return m
+}
+
+// mapPlayerCount counts players on mapID other than p. Caller must hold g.lock.
+func (g *Game) mapPlayerCount(mapID string, p *Player) int {
+	n := 0
+	for _, other := range g.players {
+		if other != p && other.MapID == mapID {
+			n++
+		}
+	}
+	return n
+}
+
+// assignChannel picks the first channel of base under its soft cap, opening
+// a new one if all are full. Maps without a cap have a single channel.
+// Caller must hold g.lock.
+func (g *Game) assignChannel(p *Player, base *WorldMap) *WorldMap {
+	if base.ChannelCap <= 0 {
+		return base
+	}
+	for _, ch := range g.channels[base.ID] {
+		if g.mapPlayerCount(ch.ID, p) < base.ChannelCap {
+			return ch
+		}
+	}
+	return g.openChannel(base)
+}
+
+// openChannel adds a copy of base as its next channel. Caller must hold g.lock.
+func (g *Game) openChannel(base *WorldMap) *WorldMap {
+	n := len(g.channels[base.ID]) + 1
+	for g.maps[fmt.Sprintf("%s@%d", base.ID, n)] != nil {
+		n++
+	}
+
+	m := NewWorldMap(fmt.Sprintf("%s@%d", base.ID, n))
+	m.cfg = base.cfg
+	m.Base = base
+	m.Channel = n
+	m.Width = base.Width
+	m.Height = base.Height
+	m.PvP = base.PvP
+	m.Damage = base.Damage
+	m.Portals = base.Portals
+	m.SetWalls(base.Walls)
+	for id, npc := range base.NPCs {
+		m.NPCs[id] = npc
+	}
+
+	g.channels[base.ID] = append(g.channels[base.ID], m)
+	g.maps[m.ID] = m
+	fmt.Printf("Opened channel %s\n", m.ID)
+	return m
+}
+
+// SwitchChannel moves p to another channel of its current map. The soft cap
+// only steers automatic assignment, so players may join a full channel to
+// meet friends.
+func (g *Game) SwitchChannel(p *Player, channel int) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	m, ok := g.maps[p.MapID]
+	if !ok || m.Channel == channel {
+		return
+	}
+	for _, ch := range g.channels[m.root().ID] {
+		if ch.Channel == channel {
+			g.switchMap(p, ch.ID, p.X, p.Y)
+			return
+		}
+	}
+}
+
+// updateChannels closes extra channels that have been empty for too long.
+// Caller must hold g.lock.
+func (g *Game) updateChannels(now time.Time, playersByMap map[string][]*Player) {
+	for baseID, chs := range g.channels {
+		kept := chs[:1]
+		for _, ch := range chs[1:] {
+			if len(playersByMap[ch.ID]) > 0 {
+				ch.emptySince = time.Time{}
+			} else if ch.emptySince.IsZero() {
+				ch.emptySince = now
+			} else if now.Sub(ch.emptySince) >= channelEmptyTimeout {
+				delete(g.maps, ch.ID)
+				fmt.Printf("Closed channel %s\n", ch.ID)
+				continue
+			}
+			kept = append(kept, ch)
+		}
+		g.channels[baseID] = kept
+	}
+}
@@ -0,0 +1,118 @@
+package game
+
+import "math"
+
+const tileSize = 20.0
+
+// Wall is a solid rectangle in world units. Walls are rasterized onto the
+// map's tile grid, so their edges should sit on multiples of tileSize.
+type Wall struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	W float64 `json:"w"`
+	H float64 `json:"h"`
+}
+
+// CollisionGrid is the tile layer of a map. A tile is solid if any wall
+// covers its center.
+type CollisionGrid struct {
+	Cols, Rows int
+	solid      []bool
+}
+
+func NewCollisionGrid(width, height float64, walls []Wall) *CollisionGrid {
+	c := &CollisionGrid{
+		Cols: int(math.Ceil(width / tileSize)),
+		Rows: int(math.Ceil(height / tileSize)),
+	}
+	c.solid = make([]bool, c.Cols*c.Rows)
+
+	for _, w := range walls {
+		for row := 0; row < c.Rows; row++ {
+			cy := (float64(row) + 0.5) * tileSize
+			if cy < w.Y || cy >= w.Y+w.H {
+				continue
+			}
+			for col := 0; col < c.Cols; col++ {
+				cx := (float64(col) + 0.5) * tileSize
+				if cx >= w.X && cx < w.X+w.W {
+					c.solid[row*c.Cols+col] = true
+				}
+			}
+		}
+	}
+	return c
+}
+
+// Solid reports whether the tile at (col, row) blocks. Tiles outside the grid
+// are open; map bounds are handled separately.
+func (c *CollisionGrid) Solid(col, row int) bool {
+	if col < 0 || row < 0 || col >= c.Cols || row >= c.Rows {
+		return false
+	}
+	return c.solid[row*c.Cols+col]
+}
+
+// Tile returns the tile containing the world point (x, y)
+func (c *CollisionGrid) Tile(x, y float64) (col, row int) {
+	return int(math.Floor(x / tileSize)), int(math.Floor(y / tileSize))
+}
+
+func (c *CollisionGrid) Blocked(x, y float64) bool {
+	return c.Solid(c.Tile(x, y))
+}
+
+// SegmentBlocked reports whether the straight line between two points
+// crosses a solid tile.
+func (c *CollisionGrid) SegmentBlocked(x0, y0, x1, y1 float64) bool {
+	dx := x1 - x0
+	dy := y1 - y0
+	steps := int(math.Ceil(math.Sqrt(dx*dx+dy*dy) / (tileSize / 2)))
+	for i := 0; i <= steps; i++ {
+		t := 1.0
+		if steps > 0 {
+			t = float64(i) / float64(steps)
+		}
+		if c.Blocked(x0+dx*t, y0+dy*t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Slide moves from (x0, y0) towards (x1, y1). If the path is blocked it
+// tries each axis alone so movers slide along walls instead of sticking.
+func (c *CollisionGrid) Slide(x0, y0, x1, y1 float64) (float64, float64) {
+	if !c.SegmentBlocked(x0, y0, x1, y1) {
+		return x1, y1
+	}
+	if !c.SegmentBlocked(x0, y0, x1, y0) {
+		return x1, y0
+	}
+	if !c.SegmentBlocked(x0, y0, x0, y1) {
+		return x0, y1
+	}
+	return x0, y0
+}
+
+// SetWalls replaces the map's collision geometry
+func (m *WorldMap) SetWalls(walls []Wall) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.Walls = walls
+	m.Collision = NewCollisionGrid(m.Width, m.Height, walls)
+}
+
+// MovePlayer moves p towards (x, y), stopping or sliding at walls
+func (g *Game) MovePlayer(p *Player, x, y float64) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if m, ok := g.maps[p.MapID]; ok {
+		m.lock.RLock()
+		x, y = m.Collision.Slide(p.X, p.Y, x, y)
+		m.lock.RUnlock()
+	}
+	p.Move(x, y)
+}
@@ -0,0 +1,42 @@
+package game
+
+// command is a client action waiting for the next tick
+type command struct {
+	player *Player
+	run    func()
+}
+
+// Enqueue schedules fn, a command from p, to run on the game loop at the
+// start of the next tick. Commands run in arrival order; those of players
+// who have left or disconnected by then are dropped.
+func (g *Game) Enqueue(p *Player, fn func()) {
+	g.queueLock.Lock()
+	defer g.queueLock.Unlock()
+
+	g.commands = append(g.commands, command{player: p, run: fn})
+}
+
+// runCommands applies the queued commands. Commands call the public Game
+// methods, so g.lock is not held; g.inputLock keeps players from joining
+// or leaving meanwhile.
+func (g *Game) runCommands() {
+	g.queueLock.Lock()
+	cmds := g.commands
+	g.commands = nil
+	g.queueLock.Unlock()
+	if len(cmds) == 0 {
+		return
+	}
+
+	g.inputLock.Lock()
+	defer g.inputLock.Unlock()
+
+	for _, c := range cmds {
+		g.lock.RLock()
+		active := g.players[c.player.ID] == c.player && !c.player.Disconnected()
+		g.lock.RUnlock()
+		if active {
+			c.run()
+		}
+	}
+}
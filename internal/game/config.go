@@ -0,0 +1,124 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Config holds the tunable gameplay settings
+type Config struct {
+	TickRate      int           `json:"tick_rate"`      // simulation ticks per second
+	InventorySize int           `json:"inventory_size"` // max inventory slots per player
+	ItemExpiry    time.Duration `json:"item_expiry"`    // how long dropped items stay on the ground
+	GoldValue     int           `json:"gold_value"`     // gold in a monster's gold drop
+	ShootCooldown time.Duration `json:"shoot_cooldown"` // delay between auto-attack shots
+	ChannelCap    int           `json:"channel_cap"`    // soft player cap per channel, 0 disables channels
+	MaxInstances  int           `json:"max_instances"`  // concurrent dungeon instances
+
+	// ReconnectGrace keeps a disconnected player in the world, frozen and
+	// invulnerable, so the client can RESUME. 0 removes players at once.
+	ReconnectGrace time.Duration `json:"reconnect_grace"`
+}
+
+func DefaultConfig() Config {
+	return Config{
+		TickRate:      30,
+		InventorySize: 20,
+		ItemExpiry:    2 * time.Minute,
+		GoldValue:     100,
+		ShootCooldown: 500 * time.Millisecond,
+		ChannelCap:    50,
+		MaxInstances:  20,
+
+		ReconnectGrace: 30 * time.Second,
+	}
+}
+
+// orDefaults replaces the settings Validate rejects with their defaults
+func (c Config) orDefaults() Config {
+	d := DefaultConfig()
+	if c.TickRate <= 0 || c.TickRate > 1000 {
+		c.TickRate = d.TickRate
+	}
+	if c.InventorySize <= 0 {
+		c.InventorySize = d.InventorySize
+	}
+	if c.ItemExpiry <= 0 {
+		c.ItemExpiry = d.ItemExpiry
+	}
+	if c.GoldValue <= 0 {
+		c.GoldValue = d.GoldValue
+	}
+	if c.ShootCooldown <= 0 {
+		c.ShootCooldown = d.ShootCooldown
+	}
+	if c.ChannelCap < 0 {
+		c.ChannelCap = d.ChannelCap
+	}
+	if c.MaxInstances < 0 {
+		c.MaxInstances = d.MaxInstances
+	}
+	if c.ReconnectGrace < 0 {
+		c.ReconnectGrace = d.ReconnectGrace
+	}
+	return c
+}
+
+func (c Config) Validate() error {
+	switch {
+	case c.TickRate <= 0 || c.TickRate > 1000:
+		return fmt.Errorf("tick_rate must be between 1 and 1000, got %d", c.TickRate)
+	case c.InventorySize <= 0:
+		return fmt.Errorf("inventory_size must be positive, got %d", c.InventorySize)
+	case c.ItemExpiry <= 0:
+		return fmt.Errorf("item_expiry must be positive, got %v", c.ItemExpiry)
+	case c.GoldValue <= 0:
+		return fmt.Errorf("gold_value must be positive, got %d", c.GoldValue)
+	case c.ShootCooldown <= 0:
+		return fmt.Errorf("shoot_cooldown must be positive, got %v", c.ShootCooldown)
+	case c.ChannelCap < 0:
+		return fmt.Errorf("channel_cap must not be negative, got %d", c.ChannelCap)
+	case c.MaxInstances < 0:
+		return fmt.Errorf("max_instances must not be negative, got %d", c.MaxInstances)
+	case c.ReconnectGrace < 0:
+		return fmt.Errorf("reconnect_grace must not be negative, got %v", c.ReconnectGrace)
+	}
+	return nil
+}
+
+// UnmarshalJSON accepts durations as strings such as "2m" or "500ms".
+// Fields missing from the input keep their current values.
+func (c *Config) UnmarshalJSON(data []byte) error {
+	type plain Config
+	aux := struct {
+		*plain
+		ItemExpiry    string `json:"item_expiry"`
+		ShootCooldown string `json:"shoot_cooldown"`
+
+		ReconnectGrace string `json:"reconnect_grace"`
+	}{plain: (*plain)(c)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	for _, d := range []struct {
+		name  string
+		value string
+		dst   *time.Duration
+	}{
+		{"item_expiry", aux.ItemExpiry, &c.ItemExpiry},
+		{"shoot_cooldown", aux.ShootCooldown, &c.ShootCooldown},
+		{"reconnect_grace", aux.ReconnectGrace, &c.ReconnectGrace},
+	} {
+		if d.value == "" {
+			continue
+		}
+		v, err := time.ParseDuration(d.value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", d.name, err)
+		}
+		*d.dst = v
+	}
+	return nil
+}
@@ -0,0 +1,254 @@
+package game
+
+import "time"
+
+const hotbarSize = 5
+
+type BuffType int
+
+const (
+	BuffSpeed BuffType = iota
+	BuffAttack
+	BuffElement
+)
+
+// ConsumableDef describes what using a consumable item does. Items refer to
+// it through Item.ConsumableID. Consumables sharing a CooldownGroup share a cooldown.
+type ConsumableDef struct {
+	ID            string
+	Name          string
+	MaxStack      int
+	Cooldown      time.Duration
+	CooldownGroup string
+
+	Heal int
+
+	Buff     BuffType
+	Amount   float64
+	Element  ProjectileType
+	Duration time.Duration // 0: no buff
+}
+
+// Buff is a timed effect applied by a consumable. Using the same consumable
+// again refreshes the buff instead of stacking it.
+type Buff struct {
+	Source    string         `json:"source"`
+	Type      BuffType       `json:"type"`
+	Amount    float64        `json:"amount"`
+	Element   ProjectileType `json:"element"`
+	ExpiresAt time.Time      `json:"expires_at"`
+}
+
+var consumableDefs = map[string]*ConsumableDef{
+	"potion_small": {
+		ID: "potion_small", Name: "Small Potion", MaxStack: 10,
+		Cooldown: 5 * time.Second, CooldownGroup: "potion",
+		Heal: 30,
+	},
+	"potion_large": {
+		ID: "potion_large", Name: "Large Potion", MaxStack: 5,
+		Cooldown: 5 * time.Second, CooldownGroup: "potion",
+		Heal: 80,
+	},
+	"elixir_speed": {
+		ID: "elixir_speed", Name: "Speed Elixir", MaxStack: 5,
+		Cooldown: 30 * time.Second, CooldownGroup: "elixir",
+		Buff: BuffSpeed, Amount: 2, Duration: 20 * time.Second,
+	},
+	"elixir_power": {
+		ID: "elixir_power", Name: "Power Elixir", MaxStack: 5,
+		Cooldown: 30 * time.Second, CooldownGroup: "elixir",
+		Buff: BuffAttack, Amount: 10, Duration: 20 * time.Second,
+	},
+	"scroll_fire": {
+		ID: "scroll_fire", Name: "Fire Scroll", MaxStack: 5,
+		Cooldown: 10 * time.Second, CooldownGroup: "scroll",
+		Buff: BuffElement, Element: ProjectileTypeFire, Duration: 30 * time.Second,
+	},
+	"scroll_water": {
+		ID: "scroll_water", Name: "Water Scroll", MaxStack: 5,
+		Cooldown: 10 * time.Second, CooldownGroup: "scroll",
+		Buff: BuffElement, Element: ProjectileTypeWater, Duration: 30 * time.Second,
+	},
+	"scroll_grass": {
+		ID: "scroll_grass", Name: "Grass Scroll", MaxStack: 5,
+		Cooldown: 10 * time.Second, CooldownGroup: "scroll",
+		Buff: BuffElement, Element: ProjectileTypeGrass, Duration: 30 * time.Second,
+	},
+}
+
+// NewConsumable creates a stack of count consumables of the given kind
+func NewConsumable(consumableID string, count int) *Item {
+	def, ok := consumableDefs[consumableID]
+	if !ok {
+		return nil
+	}
+	return &Item{
+		Type:         ItemTypeConsumable,
+		Name:         def.Name,
+		ConsumableID: def.ID,
+		Count:        count,
+	}
+}
+
+// addItem puts item into the inventory, merging consumables into existing
+// stacks first. Stacks offered in a trade are left alone so the offer can't
+// change under the partner. It reports false if the inventory has no room
+// left.
+func (p *Player) addItem(item *Item) bool {
+	if item.Type == ItemTypeConsumable {
+		if def, ok := consumableDefs[item.ConsumableID]; ok {
+			for _, it := range p.Inventory {
+				if item.Count == 0 {
+					return true
+				}
+				if it.ConsumableID != item.ConsumableID || it.Count >= def.MaxStack || p.offersInTrade(it.ID) {
+					continue
+				}
+				n := def.MaxStack - it.Count
+				if n > item.Count {
+					n = item.Count
+				}
+				it.Count += n
+				item.Count -= n
+			}
+			if item.Count == 0 {
+				return true
+			}
+		}
+	}
+
+	if len(p.Inventory) >= p.inventorySize() {
+		return false
+	}
+	p.Inventory = append(p.Inventory, item)
+	return true
+}
+
+// UseItem consumes one unit of a consumable from the inventory
+func (p *Player) UseItem(itemID int) {
+	idx := p.inventoryIndex(itemID)
+	if idx == -1 {
+		return
+	}
+	p.useInventoryItem(idx)
+}
+
+// UseHotbar uses the first stack of the consumable bound to slot that isn't
+// offered in a trade
+func (p *Player) UseHotbar(slot int) {
+	if slot < 0 || slot >= hotbarSize || p.Hotbar[slot] == "" {
+		return
+	}
+	for i, it := range p.Inventory {
+		if it.ConsumableID == p.Hotbar[slot] && !p.offersInTrade(it.ID) {
+			p.useInventoryItem(i)
+			return
+		}
+	}
+}
+
+// SetHotbar binds the consumable kind of itemID to a hotbar slot.
+// An itemID that isn't in the inventory clears the slot.
+func (p *Player) SetHotbar(slot int, itemID int) {
+	if slot < 0 || slot >= hotbarSize {
+		return
+	}
+
+	p.Hotbar[slot] = ""
+	if idx := p.inventoryIndex(itemID); idx != -1 {
+		p.Hotbar[slot] = p.Inventory[idx].ConsumableID
+	}
+	p.SendHotbar()
+}
+
+// useInventoryItem consumes one unit of the stack at idx. Stacks offered in
+// a trade can't be used until they are taken off the table.
+func (p *Player) useInventoryItem(idx int) {
+	item := p.Inventory[idx]
+	if item.Type != ItemTypeConsumable || p.offersInTrade(item.ID) {
+		return
+	}
+	def, ok := consumableDefs[item.ConsumableID]
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	if now.Before(p.Cooldowns[def.CooldownGroup]) {
+		return
+	}
+	p.Cooldowns[def.CooldownGroup] = now.Add(def.Cooldown)
+
+	if def.Heal > 0 {
+		p.HP += def.Heal
+		if p.HP > p.MaxHP {
+			p.HP = p.MaxHP
+		}
+	}
+	if def.Duration > 0 {
+		p.applyBuff(&Buff{
+			Source:    def.ID,
+			Type:      def.Buff,
+			Amount:    def.Amount,
+			Element:   def.Element,
+			ExpiresAt: now.Add(def.Duration),
+		})
+	}
+
+	item.Count--
+	if item.Count <= 0 {
+		p.Inventory = append(p.Inventory[:idx], p.Inventory[idx+1:]...)
+	}
+
+	p.SendInventory()
+	p.SendJSON(MsgCooldown{
+		Type:  "COOLDOWN",
+		Group: def.CooldownGroup,
+		Until: p.Cooldowns[def.CooldownGroup],
+	})
+	p.RecalculateStats()
+}
+
+func (p *Player) applyBuff(b *Buff) {
+	for i, existing := range p.Buffs {
+		if existing.Source == b.Source {
+			p.Buffs[i] = b
+			return
+		}
+	}
+	p.Buffs = append(p.Buffs, b)
+}
+
+// updateBuffs drops expired buffs and recalculates stats if any were removed
+func (p *Player) updateBuffs(now time.Time) {
+	kept := p.Buffs[:0]
+	for _, b := range p.Buffs {
+		if now.Before(b.ExpiresAt) {
+			kept = append(kept, b)
+		}
+	}
+	if len(kept) == len(p.Buffs) {
+		return
+	}
+	p.Buffs = kept
+	p.RecalculateStats()
+}
+
+// buffElements returns the extra projectile types granted by active buffs
+func (p *Player) buffElements() []ProjectileType {
+	var elements []ProjectileType
+	for _, b := range p.Buffs {
+		if b.Type == BuffElement {
+			elements = append(elements, b.Element)
+		}
+	}
+	return elements
+}
+
+func (p *Player) SendHotbar() {
+	p.SendJSON(MsgHotbar{
+		Type:  "HOTBAR",
+		Slots: p.Hotbar[:],
+	})
+}
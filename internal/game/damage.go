@@ -0,0 +1,135 @@
+package game
+
+import (
+	"math"
+	"math/rand"
+)
+
+// Element is the type shared by attacks and defenders when working out
+// effectiveness. Projectiles and monsters map onto it via Element().
+type Element int
+
+const (
+	ElementNeutral Element = iota
+	ElementFire
+	ElementWater
+	ElementGrass
+	elementCount
+)
+
+func (t ProjectileType) Element() Element {
+	switch t {
+	case ProjectileTypeFire:
+		return ElementFire
+	case ProjectileTypeWater:
+		return ElementWater
+	case ProjectileTypeGrass:
+		return ElementGrass
+	}
+	return ElementNeutral
+}
+
+func (t MonsterType) Element() Element {
+	switch t {
+	case MonsterTypeFire:
+		return ElementFire
+	case MonsterTypeWater:
+		return ElementWater
+	case MonsterTypeGrass:
+		return ElementGrass
+	}
+	return ElementNeutral
+}
+
+// ElementMatrix[attacker][defender] is the damage multiplier for that pairing
+type ElementMatrix [elementCount][elementCount]float64
+
+type DamageConfig struct {
+	Matrix         ElementMatrix
+	CritChance     float64
+	CritMultiplier float64
+	// Base damage is rolled uniformly between MinRoll and MaxRoll times attack
+	MinRoll float64
+	MaxRoll float64
+}
+
+func DefaultDamageConfig() *DamageConfig {
+	var m ElementMatrix
+	for a := range m {
+		for d := range m[a] {
+			m[a][d] = 1
+		}
+	}
+	m[ElementFire][ElementGrass] = 2
+	m[ElementWater][ElementFire] = 2
+	m[ElementGrass][ElementWater] = 2
+	m[ElementFire][ElementWater] = 0.5
+	m[ElementWater][ElementGrass] = 0.5
+	m[ElementGrass][ElementFire] = 0.5
+
+	return &DamageConfig{
+		Matrix:         m,
+		CritChance:     0.1,
+		CritMultiplier: 1.5,
+		MinRoll:        0.9,
+		MaxRoll:        1.1,
+	}
+}
+
+type DamageRoll struct {
+	Amount        int
+	Crit          bool
+	Effectiveness float64
+}
+
+// Roll works out the damage of one hit. Every hit deals at least 1 damage.
+func (c *DamageConfig) Roll(attack int, attacker, defender Element, defense int) DamageRoll {
+	base := float64(attack) * (c.MinRoll + rand.Float64()*(c.MaxRoll-c.MinRoll))
+
+	crit := rand.Float64() < c.CritChance
+	if crit {
+		base *= c.CritMultiplier
+	}
+
+	mult := c.Matrix[attacker][defender]
+	amount := int(math.Round(base*mult)) - defense
+	if amount < 1 {
+		amount = 1
+	}
+
+	return DamageRoll{Amount: amount, Crit: crit, Effectiveness: mult}
+}
+
+const (
+	DamageTargetMonster = "monster"
+	DamageTargetPlayer  = "player"
+)
+
+// addDamageEvent queues a DAMAGE event for the map's players. Caller must hold m.lock.
+func (m *WorldMap) addDamageEvent(target string, targetID, sourceID int, x, y float64, roll DamageRoll) {
+	m.damageEvents = append(m.damageEvents, DamageEvent{
+		Target:        target,
+		TargetID:      targetID,
+		SourceID:      sourceID,
+		X:             x,
+		Y:             y,
+		Amount:        roll.Amount,
+		Crit:          roll.Crit,
+		Effectiveness: roll.Effectiveness,
+	})
+}
+
+// FlushDamageEvents sends everything queued this tick in one DAMAGE message
+func (m *WorldMap) FlushDamageEvents(players []*Player) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if len(m.damageEvents) == 0 {
+		return
+	}
+	m.broadcastJSON(MsgDamage{
+		Type:   "DAMAGE",
+		Events: m.damageEvents,
+	}, players)
+	m.damageEvents = nil
+}
@@ -1,7 +1,23 @@
 package game
 
-// GetPlayers returns the internal players map.
+// GetPlayers returns a copy of the players map.
 // Intended for testing and debugging.
 func (g *Game) GetPlayers() map[int]*Player {
-	return g.players
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+
+	players := make(map[int]*Player, len(g.players))
+	for id, p := range g.players {
+		players[id] = p
+	}
+	return players
+}
+
+// GetMap returns the map with the given ID, or nil.
+// Intended for testing and debugging.
+func (g *Game) GetMap(id string) *WorldMap {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+
+	return g.maps[id]
 }
@@ -12,19 +12,63 @@ type Game struct {
 	players map[int]*Player
 	maps    map[string]*WorldMap
 	market  map[int]*MarketItem
+	trades  map[int]*Trade
+	duels   map[int]*Duel
+	parties map[int]*Party
+
+	partyInvites map[int]int // invited player ID -> party ID
+	instances    map[string]*Instance
+	channels     map[string][]*WorldMap // base map ID -> channels, base first
 
 	lock         sync.RWMutex
+	queueLock    sync.Mutex // guards commands
+	commands     []command
+	inputLock    sync.Mutex // held while commands run or players join or leave
 	lastID       int
 	lastMarketID int
-	quitch       chan struct{}
+	lastTradeID  int
+	lastDuelID   int
+	lastPartyID  int
+	lastItemID   int
+
+	lastInstanceID int
+	quitch         chan struct{}
+	donech         chan struct{}
+	started        bool // guarded by lock, like stopped
+	stopped        bool
+
+	cfg          Config
+	tickInterval time.Duration
+	// clock is simulated time: it starts at the wall clock and advances by
+	// exactly tickInterval per Update, so timers agree with movement
+	clock     time.Time
+	statsLock sync.Mutex
+	stats     TickStats
 }
 
-func NewGame() *Game {
+// NewGame creates the world. Settings Config.Validate would reject fall
+// back to their defaults, so even a zero Config gives a working game.
+func NewGame(cfg Config) *Game {
+	cfg = cfg.orDefaults()
 	g := &Game{
+		cfg: cfg,
+
 		players: make(map[int]*Player),
 		maps:    make(map[string]*WorldMap),
 		market:  make(map[int]*MarketItem),
-		quitch:  make(chan struct{}),
+		trades:  make(map[int]*Trade),
+		duels:   make(map[int]*Duel),
+		parties: make(map[int]*Party),
+
+		partyInvites: make(map[int]int),
+		instances:    make(map[string]*Instance),
+		channels:     make(map[string][]*WorldMap),
+		quitch:       make(chan struct{}),
+		donech:       make(chan struct{}),
+		tickInterval: time.Second / time.Duration(cfg.TickRate),
+		clock:        time.Now(),
+
+		lastItemID: gameItemIDStart,
 	}
 
 	town := NewWorldMap("town")
@@ -35,22 +79,21 @@ func NewGame() *Game {
 	g.maps["field"] = field
 	g.maps["dungeon"] = dungeon
 
-	town.NPCs[1] = &NPC{
-		ID:   1,
-		X:    400,
-		Y:    200,
-		Type: NPCTypeShop,
-		Name: "Shopkeeper",
-	}
+	dungeon.PvP = true
+	dungeon.Instanced = true
+	town.ChannelCap = cfg.ChannelCap
+	field.ChannelCap = cfg.ChannelCap
 
-	town.NPCs[2] = &NPC{
-		ID:   2,
-		X:    500,
-		Y:    200,
-		Type: NPCTypeMarket,
-		Name: "Market Manager",
+	for id, m := range g.maps {
+		m.cfg = &g.cfg
+		m.SetWalls(mapWalls[id])
+		if m.ChannelCap > 0 {
+			g.channels[id] = []*WorldMap{m}
+		}
 	}
 
+	g.spawnNPCs()
+
 	town.Portals = append(town.Portals, &Portal{
 		X:         750,
 		Y:         300,
@@ -90,27 +133,48 @@ func NewGame() *Game {
 
 func (g *Game) Start() {
 	rand.Seed(time.Now().UnixNano())
-	ticker := time.NewTicker(time.Millisecond * 33)
-	defer ticker.Stop()
+	g.lock.Lock()
+	if g.stopped {
+		g.lock.Unlock()
+		return
+	}
+	g.started = true
+	interval := g.tickInterval
+	g.lock.Unlock()
+	defer close(g.donech)
+
+	next := time.Now().Add(interval)
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
 
 	monsterTicker := time.NewTicker(time.Second * 1)
 	defer monsterTicker.Stop()
 
+	statsTicker := time.NewTicker(time.Minute)
+	defer statsTicker.Stop()
+
 	for {
 		select {
 		case <-g.quitch:
 			return
-		case <-ticker.C:
-			g.Update()
+		case <-timer.C:
+			g.runTicks(&next, interval)
+			timer.Reset(time.Until(next))
 		case <-monsterTicker.C:
 			g.SpawnMonsters()
+		case <-statsTicker.C:
+			s := g.TickStats()
+			fmt.Printf("Ticks: %d avg %v max %v overruns %d dropped %d\n", s.Ticks, s.Avg, s.Max, s.Overruns, s.Dropped)
 		}
 	}
 }
 
 func (g *Game) SpawnMonsters() {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+
 	for _, m := range g.maps {
-		if m.ID == "town" {
+		if m.root().ID == "town" || m.Instanced {
 			continue
 		}
 		m.SpawnMonster()
@@ -118,58 +182,78 @@ func (g *Game) SpawnMonsters() {
 }
 
 func (g *Game) Update() {
+	g.runCommands()
+
 	g.lock.Lock()
 	defer g.lock.Unlock()
 
+	now := time.Now()
+	g.clock = g.clock.Add(g.tickInterval)
+	dt := g.tickInterval.Seconds()
+	// Players waiting to reconnect are shown but frozen: nothing they do or
+	// suffer is simulated, so only activeByMap takes part in the update
 	playersByMap := make(map[string][]*Player)
+	activeByMap := make(map[string][]*Player)
 	for _, p := range g.players {
+		if p.Disconnected() {
+			if now.Sub(p.disconnectedAt) >= g.cfg.ReconnectGrace {
+				g.removePlayer(p)
+				continue
+			}
+			playersByMap[p.MapID] = append(playersByMap[p.MapID], p)
+			continue
+		}
+		p.updateBuffs(now)
+		p.regenMana(now)
 		g.checkPortalCollisions(p)
 		playersByMap[p.MapID] = append(playersByMap[p.MapID], p)
+		activeByMap[p.MapID] = append(activeByMap[p.MapID], p)
 	}
+	g.checkTradeDistances()
+	g.checkDuelDistances()
+	g.updateInstances(now, playersByMap)
+	g.updateChannels(now, playersByMap)
 
 	if len(g.players) == 0 {
 		return
 	}
 
 	for mapID, m := range g.maps {
+		m.setClock(g.clock)
 		mapPlayers := playersByMap[mapID]
 		if len(mapPlayers) == 0 && len(m.Projectiles) == 0 && len(m.Monsters) == 0 {
 			continue
 		}
+		active := activeByMap[mapID]
 
-		m.UpdateProjectiles()
-		m.UpdateMonsters(mapPlayers)
-		m.UpdateItems(mapPlayers)
-		m.UpdatePlayerShooting(mapPlayers)
-		m.CheckCollisions(mapPlayers)
+		m.UpdateProjectiles(g.clock, dt)
+		m.UpdateMonsters(active, dt)
+		m.UpdateMonsterAttacks(active, g.clock)
+		m.UpdateItems(active, g.clock)
+		m.UpdatePlayerShooting(active, g.clock)
+		m.CheckCollisions(active)
+		m.UpdateStatusEffects(active, g.clock)
+		m.FlushDamageEvents(mapPlayers)
 
 		snap := MsgSnap{
 			Type:        "SNAP",
 			Players:     make([]*Entity, 0, len(mapPlayers)),
 			Monsters:    make([]*Entity, 0, len(m.Monsters)),
 			Projectiles: make([]*Entity, 0, len(m.Projectiles)),
-			NPCs:        make([]*Entity, 0, len(m.NPCs)),
 		}
 
 		for _, p := range mapPlayers {
-			snap.Players = append(snap.Players, &Entity{ID: p.ID, X: p.X, Y: p.Y})
+			snap.Players = append(snap.Players, &Entity{ID: p.ID, X: p.X, Y: p.Y, Effects: p.Effects.Types(), Away: p.Disconnected()})
 		}
 		for _, mon := range m.Monsters {
 			snap.Monsters = append(snap.Monsters, &Entity{
-				ID:    mon.ID,
-				X:     mon.X,
-				Y:     mon.Y,
-				Type:  int(mon.Type),
-				HP:    mon.HP,
-				MaxHP: mon.MaxHP,
-			})
-		}
-		for _, npc := range m.NPCs {
-			snap.NPCs = append(snap.NPCs, &Entity{
-				ID:   npc.ID,
-				X:    npc.X,
-				Y:    npc.Y,
-				Type: int(npc.Type),
+				ID:      mon.ID,
+				X:       mon.X,
+				Y:       mon.Y,
+				Type:    int(mon.Type),
+				HP:      mon.HP,
+				MaxHP:   mon.MaxHP,
+				Effects: mon.Effects.Types(),
 			})
 		}
 		for _, proj := range m.Projectiles {
@@ -177,14 +261,81 @@ func (g *Game) Update() {
 		}
 
 		data, err := json.Marshal(snap)
-		if err == nil {
-			for _, p := range mapPlayers {
+		if err != nil {
+			continue
+		}
+		var legacy []byte
+		for _, p := range mapPlayers {
+			if p.Protocol >= 2 {
 				p.Send(data)
+				continue
 			}
+			if legacy == nil {
+				snap.NPCs = npcEntities(m)
+				legacy, _ = json.Marshal(snap)
+			}
+			p.Send(legacy)
+		}
+	}
+
+	for _, p := range g.players {
+		if p.HP <= 0 {
+			g.handleDeath(p)
 		}
 	}
 }
 
+// respawnPlayer brings a dead player back to town at full health
+func (g *Game) respawnPlayer(p *Player) {
+	fmt.Printf("Player %d died on %s\n", p.ID, p.MapID)
+
+	p.HP = p.MaxHP
+	p.Effects = nil
+	g.switchMap(p, g.assignChannel(p, g.maps["town"]).ID, 400, 300)
+	p.RecalculateStats()
+}
+
+// mapSwitchMsg describes map m, nil if unknown, to a player arriving at x, y
+func mapSwitchMsg(m *WorldMap, mapID string, x, y float64) MsgMapSwitch {
+	msg := MsgMapSwitch{
+		Type:    "MAP_SWITCH",
+		Map:     mapID,
+		X:       x,
+		Y:       y,
+		Channel: 1,
+	}
+	if m == nil {
+		return msg
+	}
+
+	msg.PvP = m.PvP
+	msg.Walls = m.Walls
+	msg.Channel = m.Channel
+	for _, por := range m.Portals {
+		msg.Portals = append(msg.Portals, PortalData{
+			X:      por.X,
+			Y:      por.Y,
+			Radius: por.Radius,
+			Target: por.TargetMap.ID,
+		})
+	}
+	msg.NPCs = npcEntities(m)
+	return msg
+}
+
+func npcEntities(m *WorldMap) []*Entity {
+	npcs := make([]*Entity, 0, len(m.NPCs))
+	for _, npc := range m.NPCs {
+		npcs = append(npcs, &Entity{
+			ID:   npc.ID,
+			X:    npc.X,
+			Y:    npc.Y,
+			Type: int(npc.Type),
+		})
+	}
+	return npcs
+}
+
 func (g *Game) checkPortalCollisions(p *Player) {
 	if time.Since(p.LastPortalUse) < 2*time.Second {
 		return
@@ -199,37 +350,27 @@ func (g *Game) checkPortalCollisions(p *Player) {
 		dx := p.X - portal.X
 		dy := p.Y - portal.Y
 		if dx*dx+dy*dy < portal.Radius*portal.Radius {
-			g.switchMap(p, portal.TargetMap.ID, portal.TargetX, portal.TargetY)
+			g.enterPortal(p, portal)
 			return
 		}
 	}
 }
 
 func (g *Game) switchMap(p *Player, targetMap string, targetX, targetY float64) {
+	if p.Trade != nil {
+		g.cancelTrade(p.Trade, "map_switch")
+	}
+	g.closeDialog(p)
+	if p.Duel != nil {
+		g.endDuel(p.Duel, nil, "map_switch")
+	}
+
 	p.MapID = targetMap
 	p.X = targetX
 	p.Y = targetY
 	p.LastPortalUse = time.Now()
 
-	var portals []PortalData
-	if m, ok := g.maps[targetMap]; ok {
-		for _, p := range m.Portals {
-			portals = append(portals, PortalData{
-				X:      p.X,
-				Y:      p.Y,
-				Radius: p.Radius,
-				Target: p.TargetMap.ID,
-			})
-		}
-	}
-
-	p.SendJSON(MsgMapSwitch{
-		Type:    "MAP_SWITCH",
-		Map:     targetMap,
-		X:       targetX,
-		Y:       targetY,
-		Portals: portals,
-	})
+	p.SendJSON(mapSwitchMsg(g.maps[targetMap], targetMap, targetX, targetY))
 
 	if m, ok := g.maps[targetMap]; ok {
 		for _, item := range m.Items {
@@ -244,17 +385,27 @@ func (g *Game) switchMap(p *Player, targetMap string, targetX, targetY float64)
 	}
 }
 
+// AddPlayer joins a player speaking the current protocol version
 func (g *Game) AddPlayer(conn Connection) *Player {
+	return g.JoinPlayer(conn, ProtocolVersion)
+}
+
+// JoinPlayer adds a player whose client negotiated the given protocol
+// version and sends it the game state
+func (g *Game) JoinPlayer(conn Connection, protocol int) *Player {
+	g.inputLock.Lock()
+	defer g.inputLock.Unlock()
 	g.lock.Lock()
 	defer g.lock.Unlock()
 
 	g.lastID++
 	p := NewPlayer(g.lastID, conn, g)
+	p.Protocol = protocol
 	g.players[p.ID] = p
 	fmt.Printf("Player joined: %d\n", p.ID)
 
 	// Fill inventory for testing
-	for i := 0; i < 20; i++ {
+	for i := 0; i < min(20, p.inventorySize()); i++ {
 		var item *Item
 		if i < 10 {
 			pType := ProjectileType(1 + rand.Intn(3))
@@ -275,76 +426,56 @@ func (g *Game) AddPlayer(conn Connection) *Player {
 		}
 		p.Inventory = append(p.Inventory, item)
 	}
-	p.SendInventory()
-
-	p.SendJSON(MsgWelcome{
-		Type:    "WELCOME",
-		ID:      p.ID,
-		HP:      p.HP,
-		MaxHP:   p.MaxHP,
-		Attack:  p.Attack,
-		Defense: p.Defense,
-		Speed:   p.Speed,
-		Gold:    p.Gold,
-	})
-
-	// Send initial map info (portals)
+	p.token = newSessionToken()
 	if m, ok := g.maps[p.MapID]; ok {
-		var portals []PortalData
-		for _, por := range m.Portals {
-			portals = append(portals, PortalData{
-				X:      por.X,
-				Y:      por.Y,
-				Radius: por.Radius,
-				Target: por.TargetMap.ID,
-			})
-		}
-
-		// Reuse MsgMapSwitch to set initial map and portals
-		p.SendJSON(MsgMapSwitch{
-			Type:    "MAP_SWITCH",
-			Map:     p.MapID,
-			X:       p.X,
-			Y:       p.Y,
-			Portals: portals,
-		})
-
-		for _, item := range m.Items {
-			p.SendJSON(MsgItemSpawn{
-				Type:     "ITEM_SPAWN",
-				ID:       item.ID,
-				ItemType: int(item.Type),
-				X:        item.X,
-				Y:        item.Y,
-			})
-		}
-	}
-
-	var marketItems []*MarketItem
-	for _, it := range g.market {
-		marketItems = append(marketItems, it)
+		p.MapID = g.assignChannel(p, m).ID
 	}
-	p.SendJSON(MsgMarketUpdate{
-		Type:  "MARKET_UPDATE",
-		Items: marketItems,
-	})
+	g.sendState(p)
 
 	return p
 }
 
 func (g *Game) RemovePlayer(id int) {
+	g.inputLock.Lock()
+	defer g.inputLock.Unlock()
 	g.lock.Lock()
 	defer g.lock.Unlock()
 
-	delete(g.players, id)
-	fmt.Printf("Player left: %d\n", id)
+	if p, ok := g.players[id]; ok {
+		g.removePlayer(p)
+	}
+}
+
+// removePlayer takes p out of the world for good. Caller must hold g.lock.
+func (g *Game) removePlayer(p *Player) {
+	g.leaveActivities(p)
+	g.leaveParty(p)
+	delete(g.partyInvites, p.ID)
+
+	delete(g.players, p.ID)
+	fmt.Printf("Player left: %d\n", p.ID)
 
 	g.broadcastJSON(MsgLeave{
 		Type: "LEAVE",
-		ID:   id,
+		ID:   p.ID,
 	})
 }
 
+// leaveActivities cancels the trade and forfeits the duel p is in, which
+// need the player at the keyboard. Caller must hold g.lock.
+func (g *Game) leaveActivities(p *Player) {
+	if p.Trade != nil {
+		g.cancelTrade(p.Trade, "disconnect")
+	}
+	if d := p.Duel; d != nil {
+		var winner *Player
+		if d.Accepted {
+			winner = d.opponent(p)
+		}
+		g.endDuel(d, winner, "disconnect")
+	}
+}
+
 func (g *Game) broadcastJSON(v interface{}) {
 	data, err := json.Marshal(v)
 	if err == nil {
@@ -0,0 +1,108 @@
+package game
+
+import (
+	"fmt"
+	"time"
+)
+
+// instanceEmptyTimeout is how long an instance survives with nobody in it,
+// so a party can regroup after a death or a disconnect
+const instanceEmptyTimeout = 30 * time.Second
+
+// Instance is a private copy of a template map owned by a player or a party
+type Instance struct {
+	Map        *WorldMap
+	Template   *WorldMap
+	Owner      string
+	emptySince time.Time
+}
+
+// instanceOwner keys instances by party, or by player when not in one
+func instanceOwner(p *Player) string {
+	if p.Party != nil {
+		return fmt.Sprintf("party:%d", p.Party.ID)
+	}
+	return fmt.Sprintf("player:%d", p.ID)
+}
+
+// instanceFor returns p's instance of template, creating it if needed. It
+// returns nil when the instance cap is reached. Caller must hold g.lock.
+func (g *Game) instanceFor(p *Player, template *WorldMap) *Instance {
+	owner := instanceOwner(p)
+	for _, inst := range g.instances {
+		if inst.Owner == owner && inst.Template == template {
+			return inst
+		}
+	}
+	if len(g.instances) >= g.cfg.MaxInstances {
+		return nil
+	}
+
+	g.lastInstanceID++
+	m := NewWorldMap(fmt.Sprintf("%s#%d", template.ID, g.lastInstanceID))
+	m.cfg = template.cfg
+	m.Width = template.Width
+	m.Height = template.Height
+	m.PvP = template.PvP
+	m.Damage = template.Damage
+	m.Portals = template.Portals
+	m.SetWalls(template.Walls)
+	for i := 0; i < 10; i++ {
+		m.SpawnMonster()
+	}
+
+	inst := &Instance{Map: m, Template: template, Owner: owner}
+	g.instances[m.ID] = inst
+	g.maps[m.ID] = m
+	fmt.Printf("Opened instance %s for %s\n", m.ID, owner)
+	return inst
+}
+
+// enterPortal sends p through portal, routing instanced maps to p's own
+// copy and other maps to a channel with room. Caller must hold g.lock.
+func (g *Game) enterPortal(p *Player, portal *Portal) {
+	target := portal.TargetMap
+	if target.Instanced {
+		inst := g.instanceFor(p, target)
+		if inst == nil {
+			p.LastPortalUse = time.Now()
+			p.SendJSON(MsgInstanceFull{
+				Type: "INSTANCE_FULL",
+				Map:  target.ID,
+			})
+			return
+		}
+		target = inst.Map
+	} else {
+		target = g.assignChannel(p, target)
+	}
+	g.switchMap(p, target.ID, portal.TargetX, portal.TargetY)
+}
+
+// updateInstances closes instances that have been empty for too long. Caller
+// must hold g.lock.
+func (g *Game) updateInstances(now time.Time, playersByMap map[string][]*Player) {
+	for id, inst := range g.instances {
+		if len(playersByMap[id]) > 0 {
+			inst.emptySince = time.Time{}
+			continue
+		}
+		if inst.emptySince.IsZero() {
+			inst.emptySince = now
+			continue
+		}
+		if now.Sub(inst.emptySince) >= instanceEmptyTimeout {
+			delete(g.instances, id)
+			delete(g.maps, id)
+			fmt.Printf("Closed instance %s\n", id)
+		}
+	}
+}
+
+// Instances returns the number of open instances
+func (g *Game) Instances() int {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+
+	return len(g.instances)
+}
@@ -0,0 +1,22 @@
+package game
+
+// mapWalls is the collision geometry of each map, in world units aligned to
+// tileSize. Portals, NPCs and the town spawn point must stay clear.
+var mapWalls = map[string][]Wall{
+	"town": {
+		{X: 240, Y: 120, W: 320, H: 40}, // market hall behind the NPCs
+		{X: 100, Y: 400, W: 100, H: 100},
+		{X: 600, Y: 400, W: 100, H: 100},
+	},
+	"field": {
+		{X: 200, Y: 100, W: 60, H: 60},
+		{X: 360, Y: 240, W: 40, H: 120},
+		{X: 520, Y: 420, W: 80, H: 40},
+	},
+	"dungeon": {
+		{X: 200, Y: 0, W: 40, H: 240},
+		{X: 200, Y: 360, W: 40, H: 240},
+		{X: 560, Y: 0, W: 40, H: 240},
+		{X: 560, Y: 360, W: 40, H: 240},
+	},
+}
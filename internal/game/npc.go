@@ -0,0 +1,308 @@
+package game
+
+import (
+	"math/rand"
+	"time"
+)
+
+const npcInteractRange = 100.0
+
+// Items created by the game itself (shop stock, rewards) get IDs from their
+// own range so they never collide with per-map drops.
+const gameItemIDStart = 1000000
+
+type DialogAction string
+
+const (
+	DialogActionNone   DialogAction = ""
+	DialogActionClose  DialogAction = "close"
+	DialogActionShop   DialogAction = "shop"
+	DialogActionMarket DialogAction = "market"
+	DialogActionQuest  DialogAction = "quest"
+)
+
+type DialogOption struct {
+	Text   string
+	Next   string
+	Action DialogAction
+	Quest  string // quest ID for DialogActionQuest
+}
+
+type DialogNode struct {
+	Text    string
+	Options []*DialogOption
+}
+
+// Dialog is a tree of nodes. Choosing an option runs its action and then
+// moves to Next; an empty Next ends the conversation.
+type Dialog struct {
+	Start string
+	Nodes map[string]*DialogNode
+}
+
+// DialogState tracks which node of which NPC's dialog a player is looking at.
+type DialogState struct {
+	NPCID int
+	Node  string
+}
+
+type ShopEntry struct {
+	ID       int   `json:"id"`
+	Item     *Item `json:"item"`
+	Price    int   `json:"price"`
+	Stock    int   `json:"stock"` // -1: unlimited
+	MaxStock int   `json:"-"`
+}
+
+// ShopDef describes an NPC shop. Static entries are always on sale; every
+// RotateEvery, RotationSize entries are drawn from Pool with their stock refilled.
+type ShopDef struct {
+	Static       []ShopEntry
+	Pool         []ShopEntry
+	RotationSize int
+	RotateEvery  time.Duration
+}
+
+type Shop struct {
+	def          *ShopDef
+	static       []*ShopEntry
+	rotating     []*ShopEntry
+	lastRotation time.Time
+}
+
+func NewShop(def *ShopDef) *Shop {
+	s := &Shop{def: def}
+	for _, e := range def.Static {
+		s.static = append(s.static, copyShopEntry(e))
+	}
+	return s
+}
+
+func copyShopEntry(e ShopEntry) *ShopEntry {
+	item := *e.Item
+	e.Item = &item
+	e.Stock = e.MaxStock
+	return &e
+}
+
+// refresh rotates the shop's stock if it is due
+func (s *Shop) refresh(now time.Time) {
+	if s.def.RotationSize == 0 || len(s.def.Pool) == 0 {
+		return
+	}
+	if s.rotating != nil && now.Sub(s.lastRotation) < s.def.RotateEvery {
+		return
+	}
+
+	s.lastRotation = now
+	s.rotating = s.rotating[:0]
+	for _, i := range rand.Perm(len(s.def.Pool)) {
+		if len(s.rotating) >= s.def.RotationSize {
+			break
+		}
+		s.rotating = append(s.rotating, copyShopEntry(s.def.Pool[i]))
+	}
+}
+
+func (s *Shop) Entries() []*ShopEntry {
+	entries := make([]*ShopEntry, 0, len(s.static)+len(s.rotating))
+	entries = append(entries, s.static...)
+	return append(entries, s.rotating...)
+}
+
+func (s *Shop) entry(id int) *ShopEntry {
+	for _, e := range s.Entries() {
+		if e.ID == id {
+			return e
+		}
+	}
+	return nil
+}
+
+func nearNPC(p *Player, npc *NPC) bool {
+	dx := p.X - npc.X
+	dy := p.Y - npc.Y
+	return dx*dx+dy*dy < npcInteractRange*npcInteractRange
+}
+
+// findNPC returns the NPC on p's map if p is close enough to talk to it.
+// Caller must hold g.lock.
+func (g *Game) findNPC(p *Player, npcID int) *NPC {
+	m, ok := g.maps[p.MapID]
+	if !ok {
+		return nil
+	}
+	npc, ok := m.NPCs[npcID]
+	if !ok || !nearNPC(p, npc) {
+		return nil
+	}
+	return npc
+}
+
+// Interact starts a conversation with an NPC
+func (g *Game) Interact(p *Player, npcID int) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	npc := g.findNPC(p, npcID)
+	if npc == nil {
+		return
+	}
+
+	p.onNPCInteract(npc)
+
+	if npc.Dialog == nil {
+		// NPCs without dialog go straight to what they offer
+		g.runDialogAction(p, npc, g.defaultNPCAction(npc))
+		return
+	}
+
+	g.showDialogNode(p, npc, npc.Dialog.Start)
+}
+
+// ChooseDialogOption picks an option from the node the player is looking at
+func (g *Game) ChooseDialogOption(p *Player, npcID int, option int) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	state := p.Dialog
+	if state == nil || state.NPCID != npcID {
+		return
+	}
+
+	npc := g.findNPC(p, npcID)
+	if npc == nil || npc.Dialog == nil {
+		g.closeDialog(p)
+		return
+	}
+
+	node, ok := npc.Dialog.Nodes[state.Node]
+	if !ok || option < 0 || option >= len(node.Options) {
+		return
+	}
+	opt := node.Options[option]
+
+	if opt.Action == DialogActionQuest {
+		g.acceptQuest(p, opt.Quest)
+	}
+	g.runDialogAction(p, npc, opt.Action)
+	if opt.Next == "" {
+		g.closeDialog(p)
+		return
+	}
+	g.showDialogNode(p, npc, opt.Next)
+}
+
+func (g *Game) defaultNPCAction(npc *NPC) DialogAction {
+	switch {
+	case npc.Shop != nil:
+		return DialogActionShop
+	case npc.Type == NPCTypeMarket:
+		return DialogActionMarket
+	}
+	return DialogActionNone
+}
+
+func (g *Game) showDialogNode(p *Player, npc *NPC, nodeID string) {
+	node, ok := npc.Dialog.Nodes[nodeID]
+	if !ok {
+		g.closeDialog(p)
+		return
+	}
+
+	p.Dialog = &DialogState{NPCID: npc.ID, Node: nodeID}
+
+	options := make([]string, 0, len(node.Options))
+	for _, opt := range node.Options {
+		options = append(options, opt.Text)
+	}
+	p.SendJSON(MsgDialog{
+		Type:    "DIALOG",
+		NPCID:   npc.ID,
+		NPCName: npc.Name,
+		Text:    node.Text,
+		Options: options,
+	})
+}
+
+func (g *Game) closeDialog(p *Player) {
+	if p.Dialog == nil {
+		return
+	}
+	p.SendJSON(MsgDialogClose{
+		Type:  "DIALOG_CLOSE",
+		NPCID: p.Dialog.NPCID,
+	})
+	p.Dialog = nil
+}
+
+func (g *Game) runDialogAction(p *Player, npc *NPC, action DialogAction) {
+	switch action {
+	case DialogActionShop:
+		if npc.Shop != nil {
+			g.sendShop(p, npc)
+		}
+	case DialogActionMarket:
+		var items []*MarketItem
+		for _, it := range g.market {
+			items = append(items, it)
+		}
+		p.SendJSON(MsgMarketUpdate{
+			Type:  "MARKET_UPDATE",
+			Items: items,
+		})
+	}
+}
+
+func (g *Game) sendShop(p *Player, npc *NPC) {
+	npc.Shop.refresh(time.Now())
+	p.SendJSON(MsgShop{
+		Type:  "SHOP",
+		NPCID: npc.ID,
+		Items: npc.Shop.Entries(),
+	})
+}
+
+// BuyShopItem buys one unit of a shop entry from a nearby NPC
+func (g *Game) BuyShopItem(p *Player, npcID int, entryID int) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	npc := g.findNPC(p, npcID)
+	if npc == nil || npc.Shop == nil {
+		return
+	}
+
+	npc.Shop.refresh(time.Now())
+	entry := npc.Shop.entry(entryID)
+	if entry == nil || entry.Stock == 0 {
+		return
+	}
+	if p.Gold < entry.Price {
+		return
+	}
+
+	item := *entry.Item
+	item.ID = g.newItemID()
+	if !p.addItem(&item) {
+		return
+	}
+
+	p.Gold -= entry.Price
+	if entry.Stock > 0 {
+		entry.Stock--
+	}
+
+	p.SendInventory()
+	p.SendJSON(MsgGoldUpdate{
+		Type:   "GOLD_UPDATE",
+		Amount: p.Gold,
+	})
+	g.sendShop(p, npc)
+}
+
+// newItemID hands out an ID for an item created outside of a map. Caller must hold g.lock.
+func (g *Game) newItemID() int {
+	g.lastItemID++
+	return g.lastItemID
+}
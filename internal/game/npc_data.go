@@ -0,0 +1,132 @@
+package game
+
+import "time"
+
+// NPCDef places an NPC on a map. Dialog and Shop refer to entries in
+// npcDialogs and npcShops; either may be empty.
+type NPCDef struct {
+	MapID  string
+	X, Y   float64
+	Type   NPCType
+	Name   string
+	Dialog string
+	Shop   string
+}
+
+var npcDefs = []NPCDef{
+	{MapID: "town", X: 400, Y: 200, Type: NPCTypeShop, Name: "Shopkeeper", Dialog: "shopkeeper", Shop: "general"},
+	{MapID: "town", X: 500, Y: 200, Type: NPCTypeMarket, Name: "Market Manager", Dialog: "market"},
+	{MapID: "town", X: 300, Y: 200, Type: NPCTypeTalk, Name: "Town Guide", Dialog: "guide"},
+}
+
+var npcDialogs = map[string]*Dialog{
+	"shopkeeper": {
+		Start: "greet",
+		Nodes: map[string]*DialogNode{
+			"greet": {
+				Text: "Welcome, traveler! Care to browse my wares?",
+				Options: []*DialogOption{
+					{Text: "Show me what you have.", Action: DialogActionShop},
+					{Text: "Goodbye.", Action: DialogActionClose},
+				},
+			},
+		},
+	},
+	"market": {
+		Start: "greet",
+		Nodes: map[string]*DialogNode{
+			"greet": {
+				Text: "Players list their goods with me. Take a look.",
+				Options: []*DialogOption{
+					{Text: "Open the market.", Action: DialogActionMarket},
+					{Text: "Goodbye.", Action: DialogActionClose},
+				},
+			},
+		},
+	},
+	"guide": {
+		Start: "greet",
+		Nodes: map[string]*DialogNode{
+			"greet": {
+				Text: "Hello! Need directions?",
+				Options: []*DialogOption{
+					{Text: "Where can I fight monsters?", Next: "field"},
+					{Text: "What is the dungeon?", Next: "dungeon"},
+					{Text: "Any work for me?", Next: "quests"},
+					{Text: "No thanks.", Action: DialogActionClose},
+				},
+			},
+			"field": {
+				Text: "Take the portal on the east side of town to reach the field.",
+				Options: []*DialogOption{
+					{Text: "Anything else?", Next: "greet"},
+					{Text: "Thanks!", Action: DialogActionClose},
+				},
+			},
+			"dungeon": {
+				Text: "Past the field lies the dungeon. Don't go in unprepared.",
+				Options: []*DialogOption{
+					{Text: "Anything else?", Next: "greet"},
+					{Text: "Thanks!", Action: DialogActionClose},
+				},
+			},
+			"quests": {
+				Text: "There's always something that needs doing around here.",
+				Options: []*DialogOption{
+					{Text: "I'll hunt water monsters.", Action: DialogActionQuest, Quest: "water_hunt"},
+					{Text: "I'll gather some gold.", Action: DialogActionQuest, Quest: "gold_rush"},
+					{Text: "I'll help the Shopkeeper.", Action: DialogActionQuest, Quest: "sword_delivery"},
+					{Text: "Maybe later.", Next: "greet"},
+				},
+			},
+		},
+	},
+}
+
+var npcShops = map[string]*ShopDef{
+	"general": {
+		Static: []ShopEntry{
+			{ID: 1, Item: &Item{Type: ItemTypeWeapon, Name: "Wooden Sword", Attack: 3}, Price: 50, MaxStock: -1},
+			{ID: 2, Item: &Item{Type: ItemTypeArmor, Name: "Leather Shield", Defense: 2}, Price: 50, MaxStock: -1},
+			{ID: 3, Item: NewConsumable("potion_small", 1), Price: 20, MaxStock: -1},
+			{ID: 4, Item: NewConsumable("potion_large", 1), Price: 60, MaxStock: -1},
+		},
+		Pool: []ShopEntry{
+			{ID: 101, Item: &Item{Type: ItemTypeWeapon, Name: "Flame Blade", Attack: 12, ProjectileType: ProjectileTypeFire}, Price: 300, MaxStock: 3},
+			{ID: 102, Item: &Item{Type: ItemTypeWeapon, Name: "Tide Spear", Attack: 12, ProjectileType: ProjectileTypeWater}, Price: 300, MaxStock: 3},
+			{ID: 103, Item: &Item{Type: ItemTypeWeapon, Name: "Vine Whip", Attack: 12, ProjectileType: ProjectileTypeGrass}, Price: 300, MaxStock: 3},
+			{ID: 104, Item: &Item{Type: ItemTypeArmor, Name: "Iron Shield", Defense: 8}, Price: 250, MaxStock: 2},
+			{ID: 105, Item: &Item{Type: ItemTypeArmor, Name: "Swift Boots", Speed: 1.5}, Price: 400, MaxStock: 1},
+			{ID: 106, Item: NewConsumable("elixir_speed", 1), Price: 80, MaxStock: 5},
+			{ID: 107, Item: NewConsumable("elixir_power", 1), Price: 80, MaxStock: 5},
+			{ID: 108, Item: NewConsumable("scroll_fire", 1), Price: 120, MaxStock: 3},
+			{ID: 109, Item: NewConsumable("scroll_water", 1), Price: 120, MaxStock: 3},
+			{ID: 110, Item: NewConsumable("scroll_grass", 1), Price: 120, MaxStock: 3},
+		},
+		RotationSize: 2,
+		RotateEvery:  10 * time.Minute,
+	},
+}
+
+// spawnNPCs places every NPC in npcDefs on its map
+func (g *Game) spawnNPCs() {
+	for _, def := range npcDefs {
+		m, ok := g.maps[def.MapID]
+		if !ok {
+			continue
+		}
+
+		npc := &NPC{
+			ID:     len(m.NPCs) + 1,
+			X:      def.X,
+			Y:      def.Y,
+			Type:   def.Type,
+			Name:   def.Name,
+			Dialog: npcDialogs[def.Dialog],
+		}
+		if shop, ok := npcShops[def.Shop]; ok {
+			npc.Shop = NewShop(shop)
+		}
+		m.NPCs[npc.ID] = npc
+	}
+}
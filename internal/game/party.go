@@ -0,0 +1,128 @@
+package game
+
+const maxPartySize = 4
+
+type Party struct {
+	ID       int
+	LeaderID int
+	Members  []*Player
+}
+
+// InviteToParty invites targetID into p's party, creating one if needed
+func (g *Game) InviteToParty(p *Player, targetID int) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	target, ok := g.players[targetID]
+	if !ok || target == p || target.Party != nil {
+		return
+	}
+
+	if p.Party == nil {
+		g.lastPartyID++
+		p.Party = &Party{
+			ID:       g.lastPartyID,
+			LeaderID: p.ID,
+			Members:  []*Player{p},
+		}
+		g.parties[p.Party.ID] = p.Party
+		g.sendPartyUpdate(p.Party)
+	}
+	if p.Party.LeaderID != p.ID || len(p.Party.Members) >= maxPartySize {
+		return
+	}
+
+	g.partyInvites[target.ID] = p.Party.ID
+	target.SendJSON(MsgPartyInvite{
+		Type:    "PARTY_INVITE",
+		PartyID: p.Party.ID,
+		FromID:  p.ID,
+	})
+}
+
+// AcceptParty joins a party p was invited to
+func (g *Game) AcceptParty(p *Player, partyID int) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if g.partyInvites[p.ID] != partyID || p.Party != nil {
+		return
+	}
+	delete(g.partyInvites, p.ID)
+
+	pt, ok := g.parties[partyID]
+	if !ok || len(pt.Members) >= maxPartySize {
+		return
+	}
+
+	pt.Members = append(pt.Members, p)
+	p.Party = pt
+	g.sendPartyUpdate(pt)
+}
+
+// LeaveParty removes p from its party
+func (g *Game) LeaveParty(p *Player) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	g.leaveParty(p)
+}
+
+// leaveParty removes p from its party, handing over leadership or disbanding
+// it as needed. Caller must hold g.lock.
+func (g *Game) leaveParty(p *Player) {
+	pt := p.Party
+	if pt == nil {
+		return
+	}
+	p.Party = nil
+
+	for i, m := range pt.Members {
+		if m == p {
+			pt.Members = append(pt.Members[:i], pt.Members[i+1:]...)
+			break
+		}
+	}
+	p.SendJSON(MsgPartyUpdate{Type: "PARTY_UPDATE"})
+
+	if len(pt.Members) <= 1 {
+		delete(g.parties, pt.ID)
+		for _, m := range pt.Members {
+			m.Party = nil
+			m.SendJSON(MsgPartyUpdate{Type: "PARTY_UPDATE"})
+		}
+		for targetID, partyID := range g.partyInvites {
+			if partyID == pt.ID {
+				delete(g.partyInvites, targetID)
+			}
+		}
+		return
+	}
+
+	if pt.LeaderID == p.ID {
+		pt.LeaderID = pt.Members[0].ID
+	}
+	g.sendPartyUpdate(pt)
+}
+
+func (g *Game) sendPartyUpdate(pt *Party) {
+	members := make([]int, 0, len(pt.Members))
+	for _, m := range pt.Members {
+		members = append(members, m.ID)
+	}
+
+	msg := MsgPartyUpdate{
+		Type:     "PARTY_UPDATE",
+		PartyID:  pt.ID,
+		LeaderID: pt.LeaderID,
+		Members:  members,
+	}
+	for _, m := range pt.Members {
+		m.SendJSON(msg)
+	}
+}
+
+// sameParty reports whether a and b are in the same party
+func sameParty(a, b *Player) bool {
+	return a.Party != nil && a.Party == b.Party
+}
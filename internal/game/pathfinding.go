@@ -0,0 +1,170 @@
+package game
+
+import (
+	"container/heap"
+	"math"
+	"time"
+)
+
+const (
+	// maxPathsPerTick caps A* searches per map per tick. Monsters over the
+	// budget keep their cached path, or walk straight, until a later tick.
+	maxPathsPerTick = 8
+	// repathInterval is how long a cached path is trusted while its goal
+	// tile stays the same
+	repathInterval = time.Second
+	// maxPathNodes bounds how many tiles a single search may expand
+	maxPathNodes = 2000
+)
+
+type Waypoint struct {
+	X, Y float64
+}
+
+type pathNode struct {
+	tile  int
+	f     float64
+	index int
+}
+
+type pathQueue []*pathNode
+
+func (q pathQueue) Len() int           { return len(q) }
+func (q pathQueue) Less(i, j int) bool { return q[i].f < q[j].f }
+func (q pathQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+func (q *pathQueue) Push(x interface{}) {
+	n := x.(*pathNode)
+	n.index = len(*q)
+	*q = append(*q, n)
+}
+func (q *pathQueue) Pop() interface{} {
+	old := *q
+	n := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return n
+}
+
+var pathDirs = [8][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}, {1, 1}, {1, -1}, {-1, 1}, {-1, -1}}
+
+// FindPath runs A* over the tile grid from (x0, y0) to (x1, y1). It returns
+// the waypoints to walk through, ending at the goal, or nil if the goal is
+// unreachable within maxPathNodes expansions. Diagonal steps may not cut
+// wall corners.
+func (c *CollisionGrid) FindPath(x0, y0, x1, y1 float64) []Waypoint {
+	sc, sr := c.Tile(x0, y0)
+	gc, gr := c.Tile(x1, y1)
+	if !c.inside(sc, sr) || !c.inside(gc, gr) || c.Solid(gc, gr) {
+		return nil
+	}
+
+	start := sr*c.Cols + sc
+	goal := gr*c.Cols + gc
+
+	h := func(tile int) float64 {
+		dc := math.Abs(float64(tile%c.Cols - gc))
+		dr := math.Abs(float64(tile/c.Cols - gr))
+		return math.Max(dc, dr) + (math.Sqrt2-1)*math.Min(dc, dr)
+	}
+
+	cost := map[int]float64{start: 0}
+	from := map[int]int{}
+	closed := map[int]bool{}
+	open := &pathQueue{{tile: start, f: h(start)}}
+
+	for expanded := 0; open.Len() > 0 && expanded < maxPathNodes; expanded++ {
+		cur := heap.Pop(open).(*pathNode).tile
+		if cur == goal {
+			return c.buildPath(from, start, goal, x1, y1)
+		}
+		if closed[cur] {
+			continue
+		}
+		closed[cur] = true
+
+		col, row := cur%c.Cols, cur/c.Cols
+		for _, d := range pathDirs {
+			nc, nr := col+d[0], row+d[1]
+			if !c.inside(nc, nr) || c.Solid(nc, nr) {
+				continue
+			}
+			step := 1.0
+			if d[0] != 0 && d[1] != 0 {
+				if c.Solid(col+d[0], row) || c.Solid(col, row+d[1]) {
+					continue
+				}
+				step = math.Sqrt2
+			}
+
+			next := nr*c.Cols + nc
+			g := cost[cur] + step
+			if old, ok := cost[next]; ok && g >= old {
+				continue
+			}
+			cost[next] = g
+			from[next] = cur
+			heap.Push(open, &pathNode{tile: next, f: g + h(next)})
+		}
+	}
+	return nil
+}
+
+func (c *CollisionGrid) inside(col, row int) bool {
+	return col >= 0 && row >= 0 && col < c.Cols && row < c.Rows
+}
+
+func (c *CollisionGrid) buildPath(from map[int]int, start, goal int, x, y float64) []Waypoint {
+	var tiles []int
+	for t := goal; t != start; t = from[t] {
+		tiles = append(tiles, t)
+	}
+
+	path := make([]Waypoint, 0, len(tiles))
+	for i := len(tiles) - 1; i > 0; i-- {
+		t := tiles[i]
+		path = append(path, Waypoint{
+			X: (float64(t%c.Cols) + 0.5) * tileSize,
+			Y: (float64(t/c.Cols) + 0.5) * tileSize,
+		})
+	}
+	return append(path, Waypoint{X: x, Y: y})
+}
+
+// steerMonster picks the point mon should walk towards to reach (tx, ty).
+// With a clear line it heads straight there; otherwise it follows a cached
+// A* path, searching for a new one when the goal tile changes or the path
+// gets old and *budget allows. Caller must hold m.lock.
+func (m *WorldMap) steerMonster(mon *Monster, tx, ty float64, now time.Time, budget *int) (float64, float64) {
+	c := m.Collision
+	if !c.SegmentBlocked(mon.X, mon.Y, tx, ty) {
+		mon.path = nil
+		mon.pathAt = time.Time{}
+		return tx, ty
+	}
+
+	gc, gr := c.Tile(tx, ty)
+	goal := gr*c.Cols + gc
+	stale := mon.pathAt.IsZero() || mon.pathGoal != goal || now.Sub(mon.pathAt) > repathInterval
+	if stale && *budget > 0 {
+		*budget--
+		mon.path = c.FindPath(mon.X, mon.Y, tx, ty)
+		mon.pathGoal = goal
+		mon.pathAt = now
+	}
+
+	for len(mon.path) > 1 {
+		dx := mon.path[0].X - mon.X
+		dy := mon.path[0].Y - mon.Y
+		if dx*dx+dy*dy > tileSize*tileSize/4 {
+			break
+		}
+		mon.path = mon.path[1:]
+	}
+	if len(mon.path) == 0 {
+		return tx, ty
+	}
+	return mon.path[0].X, mon.path[0].Y
+}
@@ -30,6 +30,33 @@ type Player struct {
 	Defense int
 	Speed   float64
 	Gold    int
+	XP      int
+	Mana    int
+	MaxMana int
+
+	AutoAttack    bool
+	lastManaRegen time.Time
+
+	Quests          map[string]*QuestState
+	CompletedQuests map[string]bool
+
+	Hotbar    [hotbarSize]string
+	Cooldowns map[string]time.Time
+	Buffs     []*Buff
+	Effects   StatusEffects
+
+	Trade  *Trade
+	Dialog *DialogState
+	Duel   *Duel
+	Party  *Party
+
+	PvPKills       int
+	PvPDeaths      int
+	lastAttackerID int
+
+	Protocol       int       // negotiated protocol version
+	token          string    // secret for RESUME
+	disconnectedAt time.Time // zero while connected
 
 	game *Game
 }
@@ -41,6 +68,7 @@ func (p *Player) Game() *Game {
 func NewPlayer(id int, conn Connection, g *Game) *Player {
 	return &Player{
 		ID:        id,
+		Protocol:  ProtocolVersion,
 		MapID:     "town",
 		Conn:      conn,
 		X:         400,
@@ -55,7 +83,16 @@ func NewPlayer(id int, conn Connection, g *Game) *Player {
 		Defense: 0,
 		Speed:   5.0,
 		Gold:    0,
-		game:    g,
+		Mana:    100,
+		MaxMana: 100,
+
+		AutoAttack: true,
+
+		Quests:          make(map[string]*QuestState),
+		CompletedQuests: make(map[string]bool),
+		Cooldowns:       make(map[string]time.Time),
+
+		game: g,
 	}
 }
 
@@ -77,6 +114,9 @@ func (p *Player) Equip(itemID int, slot int) {
 	}
 
 	item := p.Inventory[itemIdx]
+	if item.Type == ItemTypeConsumable {
+		return
+	}
 
 	if p.Equipment[slot] != nil {
 		p.Unequip(slot)
@@ -113,13 +153,9 @@ func (p *Player) Sell(itemID int) {
 		if ok {
 			nearShop := false
 			for _, npc := range m.NPCs {
-				if npc.Type == NPCTypeShop {
-					dx := p.X - npc.X
-					dy := p.Y - npc.Y
-					if dx*dx+dy*dy < 100*100 {
-						nearShop = true
-						break
-					}
+				if npc.Type == NPCTypeShop && nearNPC(p, npc) {
+					nearShop = true
+					break
 				}
 			}
 			if !nearShop {
@@ -145,6 +181,10 @@ func (p *Player) Sell(itemID int) {
 	// Price formula: 10 + (Atk + Def + Spd) * 5
 	statsSum := item.Attack + item.Defense + int(item.Speed)
 	price := 10 + statsSum*5
+	// A consumable stack sells every unit in it
+	if item.Count > 1 {
+		price *= item.Count
+	}
 
 	p.Gold += price
 
@@ -172,10 +212,23 @@ func (p *Player) RecalculateStats() {
 		}
 	}
 
+	for _, b := range p.Buffs {
+		switch b.Type {
+		case BuffAttack:
+			atk += int(b.Amount)
+		case BuffSpeed:
+			spd += b.Amount
+		}
+	}
+
 	p.Attack = atk
 	p.Defense = def
-	p.Speed = spd
+	p.Speed = spd * p.Effects.SpeedFactor()
+
+	p.SendStats()
+}
 
+func (p *Player) SendStats() {
 	p.SendJSON(MsgWelcome{
 		Type:    "STATS",
 		ID:      p.ID,
@@ -185,6 +238,12 @@ func (p *Player) RecalculateStats() {
 		Defense: p.Defense,
 		Speed:   p.Speed,
 		Gold:    p.Gold,
+		XP:      p.XP,
+		Mana:    p.Mana,
+		MaxMana: p.MaxMana,
+		Buffs:   p.Buffs,
+
+		AutoAttack: p.AutoAttack,
 	})
 }
 
@@ -209,6 +268,9 @@ func (p *Player) SendEquipment() {
 }
 
 func (p *Player) Move(x, y float64) {
+	if p.Effects.Has(StatusRoot) {
+		return
+	}
 	dx := x - p.X
 	dy := y - p.Y
 	if dx != 0 || dy != 0 {
@@ -234,6 +296,32 @@ func (p *Player) SendJSON(v interface{}) {
 	}
 }
 
+// inventoryIndex returns the position of itemID in the inventory, or -1.
+func (p *Player) inventoryIndex(itemID int) int {
+	for i, it := range p.Inventory {
+		if it.ID == itemID {
+			return i
+		}
+	}
+	return -1
+}
+
+// inventorySize is the number of inventory slots from the game config
+func (p *Player) inventorySize() int {
+	if p.game != nil {
+		return p.game.cfg.InventorySize
+	}
+	return DefaultConfig().InventorySize
+}
+
+// clock is the game time, or the wall clock for players outside a game
+func (p *Player) clock() time.Time {
+	if p.game != nil {
+		return p.game.clock
+	}
+	return time.Now()
+}
+
 func (p *Player) String() string {
 	return fmt.Sprintf("Player %d [%.2f, %.2f] Inv: %d", p.ID, p.X, p.Y, len(p.Inventory))
 }
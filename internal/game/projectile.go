@@ -0,0 +1,138 @@
+package game
+
+import (
+	"math"
+	"time"
+)
+
+// ProjectileDef holds the flight and hit properties of a projectile type.
+// Weapons pick theirs through Item.ProjectileType.
+type ProjectileDef struct {
+	Speed     float64       // distance per second
+	Range     float64       // max distance travelled, 0 for no limit
+	Lifetime  time.Duration // max time in flight, 0 for no limit
+	HitRadius float64
+	Pierce    int     // extra targets it passes through before being removed
+	Homing    float64 // max turn per second towards the nearest monster, in radians
+
+	SplashRadius float64 // monsters this close to the one hit take splash damage
+	SplashFactor float64 // share of the hit's attack dealt as splash
+}
+
+var projectileDefs = map[ProjectileType]*ProjectileDef{
+	ProjectileTypeDefault: {Speed: 300, Range: 500, Lifetime: 2 * time.Second, HitRadius: 20},
+	ProjectileTypeFire:    {Speed: 270, Range: 450, Lifetime: 2 * time.Second, HitRadius: 20, SplashRadius: 50, SplashFactor: 0.5},
+	ProjectileTypeWater:   {Speed: 360, Range: 600, Lifetime: 2 * time.Second, HitRadius: 20, Pierce: 2},
+	ProjectileTypeGrass:   {Speed: 240, Range: 400, Lifetime: 3 * time.Second, HitRadius: 40, Homing: 2.4},
+}
+
+func (p *Projectile) def() *ProjectileDef {
+	if def, ok := projectileDefs[p.Type]; ok {
+		return def
+	}
+	return projectileDefs[ProjectileTypeDefault]
+}
+
+// attack is the attack value the projectile hits with
+func (p *Projectile) attack(owner *Player) int {
+	attack := 10
+	if owner != nil {
+		attack = owner.Attack
+	}
+	if p.Power > 0 {
+		attack = int(float64(attack) * p.Power)
+	}
+	return attack
+}
+
+// pierce uses up one pierce charge. It reports false once the projectile
+// should be removed.
+func (p *Projectile) pierce() bool {
+	if p.PierceLeft > 0 {
+		p.PierceLeft--
+		return true
+	}
+	return false
+}
+
+// steer turns the projectile towards the nearest living monster by at most
+// turn radians. Caller must hold m.lock.
+func (m *WorldMap) steer(p *Projectile, turn float64) {
+	var target *Monster
+	minDist := math.MaxFloat64
+	for _, mon := range m.Monsters {
+		if mon.HP <= 0 || p.hitMonsters[mon.ID] {
+			continue
+		}
+		dx := mon.X - p.X
+		dy := mon.Y - p.Y
+		if dist := dx*dx + dy*dy; dist < minDist {
+			minDist = dist
+			target = mon
+		}
+	}
+	if target == nil {
+		return
+	}
+
+	current := math.Atan2(p.VY, p.VX)
+	diff := math.Atan2(target.Y-p.Y, target.X-p.X) - current
+	for diff > math.Pi {
+		diff -= 2 * math.Pi
+	}
+	for diff < -math.Pi {
+		diff += 2 * math.Pi
+	}
+	if diff > turn {
+		diff = turn
+	} else if diff < -turn {
+		diff = -turn
+	}
+
+	angle := current + diff
+	p.VX = math.Cos(angle)
+	p.VY = math.Sin(angle)
+}
+
+// projectileHitMonster applies a projectile hit, and its splash, to mon.
+// Caller must hold m.lock.
+func (m *WorldMap) projectileHitMonster(proj *Projectile, owner *Player, mon *Monster, players []*Player) {
+	if proj.hitMonsters == nil {
+		proj.hitMonsters = make(map[int]bool)
+	}
+	proj.hitMonsters[mon.ID] = true
+
+	attack := proj.attack(owner)
+	m.damageMonster(mon, owner, proj.OwnerID, attack, proj.Type, players)
+
+	def := proj.def()
+	if def.SplashRadius <= 0 {
+		return
+	}
+	splash := int(float64(attack) * def.SplashFactor)
+	for _, other := range m.Monsters {
+		if other == mon || other.HP <= 0 {
+			continue
+		}
+		dx := other.X - mon.X
+		dy := other.Y - mon.Y
+		if dx*dx+dy*dy < def.SplashRadius*def.SplashRadius {
+			m.damageMonster(other, owner, proj.OwnerID, splash, proj.Type, players)
+		}
+	}
+}
+
+// damageMonster rolls and applies one hit on a monster, killing it if needed.
+// Caller must hold m.lock.
+func (m *WorldMap) damageMonster(mon *Monster, owner *Player, sourceID int, attack int, element ProjectileType, players []*Player) {
+	roll := m.Damage.Roll(attack, element.Element(), mon.Type.Element(), 0)
+	mon.HP -= roll.Amount
+	m.addDamageEvent(DamageTargetMonster, mon.ID, sourceID, mon.X, mon.Y, roll)
+	if status, ok := elementStatus[element]; ok {
+		mon.Effects.Apply(status, sourceID, m.clock())
+	}
+
+	if mon.HP <= 0 {
+		m.killMonster(mon, owner, players)
+	}
+}
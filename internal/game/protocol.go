@@ -1,5 +1,39 @@
 package game
 
+import "time"
+
+// Protocol versions the server speaks. Version 2 sends a map's NPCs once in
+// MAP_SWITCH instead of in every SNAP; version 1 clients, including those
+// that predate HELLO, still get them in SNAP.
+const (
+	ProtocolVersion    = 2
+	MinProtocolVersion = 1
+)
+
+// MsgHello - Client -> Server. Must be the first message on a connection.
+type MsgHello struct {
+	Type         string   `json:"type"`
+	Version      int      `json:"version"`
+	Codecs       []string `json:"codecs"`       // encodings the client reads, in order of preference
+	Capabilities []string `json:"capabilities"` // optional features the client supports
+}
+
+// MsgHelloOK - Server -> Client, followed by the game state
+type MsgHelloOK struct {
+	Type         string   `json:"type"`
+	Version      int      `json:"version"`
+	Codec        string   `json:"codec"`
+	Capabilities []string `json:"capabilities"` // features enabled for this connection
+}
+
+// MsgHelloRejected - Server -> Client, after which the connection is closed
+type MsgHelloRejected struct {
+	Type       string `json:"type"`
+	Reason     string `json:"reason"`
+	MinVersion int    `json:"min_version"`
+	MaxVersion int    `json:"max_version"`
+}
+
 // MsgWelcome - Server -> Client
 type MsgWelcome struct {
 	Type    string  `json:"type"`
@@ -10,6 +44,16 @@ type MsgWelcome struct {
 	Defense int     `json:"defense"`
 	Speed   float64 `json:"speed"`
 	Gold    int     `json:"gold"`
+	XP      int     `json:"xp"`
+	Mana    int     `json:"mana"`
+	MaxMana int     `json:"max_mana"`
+	Buffs   []*Buff `json:"buffs,omitempty"`
+
+	AutoAttack bool `json:"auto_attack"`
+
+	// Token lets the client RESUME this player after a dropped connection.
+	// Only sent in WELCOME.
+	Token string `json:"token,omitempty"`
 }
 
 type Entity struct {
@@ -19,6 +63,9 @@ type Entity struct {
 	Type  int     `json:"type"`
 	HP    int     `json:"hp,omitempty"`
 	MaxHP int     `json:"max_hp,omitempty"`
+
+	Effects []int `json:"effects,omitempty"` // active StatusType values
+	Away    bool  `json:"away,omitempty"`    // player is disconnected and may come back
 }
 
 // MsgSnap - Server -> Client
@@ -27,7 +74,7 @@ type MsgSnap struct {
 	Players     []*Entity `json:"players"`
 	Monsters    []*Entity `json:"monsters"`
 	Projectiles []*Entity `json:"projectiles"`
-	NPCs        []*Entity `json:"npcs"`
+	NPCs        []*Entity `json:"npcs,omitempty"` // protocol version 1 only
 }
 
 // MsgItemSpawn - Server -> Client
@@ -71,6 +118,16 @@ type MsgMapSwitch struct {
 	X       float64      `json:"x"`
 	Y       float64      `json:"y"`
 	Portals []PortalData `json:"portals"`
+	PvP     bool         `json:"pvp"`
+	Walls   []Wall       `json:"walls"`
+	Channel int          `json:"channel"`
+	NPCs    []*Entity    `json:"npcs"`
+}
+
+// MsgChannelSwitch - Client -> Server
+type MsgChannelSwitch struct {
+	Type    string `json:"type"`
+	Channel int    `json:"channel"`
 }
 
 // MsgInventory - Server -> Client
@@ -129,3 +186,288 @@ type MsgMarketUpdate struct {
 	Type  string        `json:"type"`
 	Items []*MarketItem `json:"items"`
 }
+
+// MsgTradeRequest - Client -> Server
+type MsgTradeRequest struct {
+	Type     string `json:"type"`
+	TargetID int    `json:"target_id"`
+}
+
+// MsgTradeAccept - Client -> Server
+type MsgTradeAccept struct {
+	Type    string `json:"type"`
+	TradeID int    `json:"trade_id"`
+}
+
+// MsgTradeItem - Client -> Server (TRADE_ADD_ITEM, TRADE_REMOVE_ITEM)
+type MsgTradeItem struct {
+	Type   string `json:"type"`
+	ItemID int    `json:"item_id"`
+}
+
+// MsgTradeGold - Client -> Server
+type MsgTradeGold struct {
+	Type   string `json:"type"`
+	Amount int    `json:"amount"`
+}
+
+// MsgTradeInvite - Server -> Client
+type MsgTradeInvite struct {
+	Type    string `json:"type"`
+	TradeID int    `json:"trade_id"`
+	FromID  int    `json:"from_id"`
+}
+
+// MsgTradeUpdate - Server -> Client
+type MsgTradeUpdate struct {
+	Type           string  `json:"type"`
+	TradeID        int     `json:"trade_id"`
+	PartnerID      int     `json:"partner_id"`
+	MyItems        []*Item `json:"my_items"`
+	MyGold         int     `json:"my_gold"`
+	MyConfirmed    bool    `json:"my_confirmed"`
+	TheirItems     []*Item `json:"their_items"`
+	TheirGold      int     `json:"their_gold"`
+	TheirConfirmed bool    `json:"their_confirmed"`
+}
+
+// MsgTradeClosed - Server -> Client
+type MsgTradeClosed struct {
+	Type    string `json:"type"`
+	TradeID int    `json:"trade_id"`
+	Reason  string `json:"reason"`
+}
+
+// MsgInteract - Client -> Server
+type MsgInteract struct {
+	Type  string `json:"type"`
+	NPCID int    `json:"npc_id"`
+}
+
+// MsgDialogChoice - Client -> Server
+type MsgDialogChoice struct {
+	Type   string `json:"type"`
+	NPCID  int    `json:"npc_id"`
+	Option int    `json:"option"`
+}
+
+// MsgBuy - Client -> Server
+type MsgBuy struct {
+	Type    string `json:"type"`
+	NPCID   int    `json:"npc_id"`
+	EntryID int    `json:"entry_id"`
+}
+
+// MsgDialog - Server -> Client
+type MsgDialog struct {
+	Type    string   `json:"type"`
+	NPCID   int      `json:"npc_id"`
+	NPCName string   `json:"npc_name"`
+	Text    string   `json:"text"`
+	Options []string `json:"options"`
+}
+
+// MsgDialogClose - Server -> Client
+type MsgDialogClose struct {
+	Type  string `json:"type"`
+	NPCID int    `json:"npc_id"`
+}
+
+// MsgShop - Server -> Client
+type MsgShop struct {
+	Type  string       `json:"type"`
+	NPCID int          `json:"npc_id"`
+	Items []*ShopEntry `json:"items"`
+}
+
+type QuestObjectiveData struct {
+	Description string `json:"description"`
+	Progress    int    `json:"progress"`
+	Count       int    `json:"count"`
+}
+
+// MsgQuestUpdate - Server -> Client
+type MsgQuestUpdate struct {
+	Type       string               `json:"type"`
+	QuestID    string               `json:"quest_id"`
+	Name       string               `json:"name"`
+	Objectives []QuestObjectiveData `json:"objectives"`
+	Completed  bool                 `json:"completed"`
+}
+
+// MsgQuestAbandon - Client -> Server, echoed back as confirmation
+type MsgQuestAbandon struct {
+	Type    string `json:"type"`
+	QuestID string `json:"quest_id"`
+}
+
+// MsgUseItem - Client -> Server
+type MsgUseItem struct {
+	Type   string `json:"type"`
+	ItemID int    `json:"item_id"`
+}
+
+// MsgUseHotbar - Client -> Server
+type MsgUseHotbar struct {
+	Type string `json:"type"`
+	Slot int    `json:"slot"`
+}
+
+// MsgHotbarSet - Client -> Server
+type MsgHotbarSet struct {
+	Type   string `json:"type"`
+	Slot   int    `json:"slot"`
+	ItemID int    `json:"item_id"`
+}
+
+// MsgHotbar - Server -> Client
+type MsgHotbar struct {
+	Type  string   `json:"type"`
+	Slots []string `json:"slots"` // consumable IDs, "" for empty
+}
+
+// MsgCooldown - Server -> Client
+type MsgCooldown struct {
+	Type  string    `json:"type"`
+	Group string    `json:"group"`
+	Until time.Time `json:"until"`
+}
+
+type DamageEvent struct {
+	Target        string  `json:"target"` // "monster" or "player"
+	TargetID      int     `json:"target_id"`
+	SourceID      int     `json:"source_id,omitempty"`
+	X             float64 `json:"x"`
+	Y             float64 `json:"y"`
+	Amount        int     `json:"amount"`
+	Crit          bool    `json:"crit,omitempty"`
+	Effectiveness float64 `json:"effectiveness"`
+}
+
+// MsgDamage - Server -> Client
+type MsgDamage struct {
+	Type   string        `json:"type"`
+	Events []DamageEvent `json:"events"`
+}
+
+// MsgCast - Client -> Server
+type MsgCast struct {
+	Type     string  `json:"type"`
+	SkillID  string  `json:"skill_id"`
+	TargetID int     `json:"target_id"`
+	DirX     float64 `json:"dir_x"`
+	DirY     float64 `json:"dir_y"`
+}
+
+// MsgAutoAttack - Client -> Server
+type MsgAutoAttack struct {
+	Type    string `json:"type"`
+	Enabled bool   `json:"enabled"`
+}
+
+type SkillData struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	CooldownMs int64  `json:"cooldown_ms"`
+	ManaCost   int    `json:"mana_cost"`
+	Targeting  int    `json:"targeting"` // TargetMode
+}
+
+// MsgSkills - Server -> Client
+type MsgSkills struct {
+	Type   string      `json:"type"`
+	Skills []SkillData `json:"skills"`
+}
+
+// MsgDuelRequest - Client -> Server
+type MsgDuelRequest struct {
+	Type     string `json:"type"`
+	TargetID int    `json:"target_id"`
+}
+
+// MsgDuelAccept - Client -> Server
+type MsgDuelAccept struct {
+	Type   string `json:"type"`
+	DuelID int    `json:"duel_id"`
+}
+
+// MsgDuelInvite - Server -> Client
+type MsgDuelInvite struct {
+	Type   string `json:"type"`
+	DuelID int    `json:"duel_id"`
+	FromID int    `json:"from_id"`
+}
+
+// MsgDuelStart - Server -> Client
+type MsgDuelStart struct {
+	Type       string `json:"type"`
+	DuelID     int    `json:"duel_id"`
+	OpponentID int    `json:"opponent_id"`
+}
+
+// MsgDuelEnd - Server -> Client
+type MsgDuelEnd struct {
+	Type     string `json:"type"`
+	DuelID   int    `json:"duel_id"`
+	WinnerID int    `json:"winner_id,omitempty"`
+	Reason   string `json:"reason"`
+}
+
+// MsgPvPKill - Server -> Client
+type MsgPvPKill struct {
+	Type     string `json:"type"`
+	KillerID int    `json:"killer_id"`
+	VictimID int    `json:"victim_id"`
+	GoldLost int    `json:"gold_lost"`
+}
+
+// MsgPartyRequest - Client -> Server (PARTY_INVITE)
+type MsgPartyRequest struct {
+	Type     string `json:"type"`
+	TargetID int    `json:"target_id"`
+}
+
+// MsgPartyAccept - Client -> Server
+type MsgPartyAccept struct {
+	Type    string `json:"type"`
+	PartyID int    `json:"party_id"`
+}
+
+// MsgPartyInvite - Server -> Client
+type MsgPartyInvite struct {
+	Type    string `json:"type"`
+	PartyID int    `json:"party_id"`
+	FromID  int    `json:"from_id"`
+}
+
+// MsgPartyUpdate - Server -> Client. An empty party_id means no party.
+type MsgPartyUpdate struct {
+	Type     string `json:"type"`
+	PartyID  int    `json:"party_id"`
+	LeaderID int    `json:"leader_id"`
+	Members  []int  `json:"members"`
+}
+
+// MsgInstanceFull - Server -> Client
+type MsgInstanceFull struct {
+	Type string `json:"type"`
+	Map  string `json:"map"`
+}
+
+// MsgServerShutdown - Server -> Client
+type MsgServerShutdown struct {
+	Type    string `json:"type"`
+	Seconds int    `json:"seconds"`
+}
+
+// MsgResume - Client -> Server
+type MsgResume struct {
+	Type  string `json:"type"`
+	Token string `json:"token"`
+}
+
+// MsgResumeFailed - Server -> Client. The client keeps the new player it
+// was given on connect.
+type MsgResumeFailed struct {
+	Type string `json:"type"`
+}
@@ -0,0 +1,213 @@
+package game
+
+import "fmt"
+
+const (
+	duelRequestRange = 150.0
+	duelRange        = 500.0
+
+	// pvpGoldPenalty is the share of gold a player drops when killed by another player
+	pvpGoldPenalty = 0.1
+)
+
+// Duel is a consensual fight between two players that works on any map.
+// The loser is left at 1 HP instead of dying.
+type Duel struct {
+	ID       int
+	From     *Player
+	To       *Player
+	Accepted bool
+}
+
+func (d *Duel) opponent(p *Player) *Player {
+	if d.From == p {
+		return d.To
+	}
+	return d.From
+}
+
+func inRange(a, b *Player, r float64) bool {
+	if a.MapID != b.MapID {
+		return false
+	}
+	dx := a.X - b.X
+	dy := a.Y - b.Y
+	return dx*dx+dy*dy < r*r
+}
+
+func dueling(a, b *Player) bool {
+	return a.Duel != nil && a.Duel.Accepted && a.Duel == b.Duel
+}
+
+// canDamage decides whether attacker's hits land on target. Duel opponents
+// can always hit each other; otherwise it takes a PvP map and no shared party.
+func (m *WorldMap) canDamage(attacker, target *Player) bool {
+	if attacker == nil || attacker == target || target.HP <= 0 {
+		return false
+	}
+	if dueling(attacker, target) {
+		return true
+	}
+	return m.PvP && !sameParty(attacker, target)
+}
+
+// hitPlayer applies a player's attack to another player. Caller must hold m.lock.
+func (m *WorldMap) hitPlayer(attacker, target *Player, attack int, element ProjectileType) {
+	roll := m.Damage.Roll(attack, element.Element(), ElementNeutral, target.Defense)
+	target.HP -= roll.Amount
+	if target.HP < 0 {
+		target.HP = 0
+	}
+	target.lastAttackerID = attacker.ID
+	m.addDamageEvent(DamageTargetPlayer, target.ID, attacker.ID, target.X, target.Y, roll)
+
+	if status, ok := elementStatus[element]; ok {
+		target.ApplyStatus(status, attacker.ID)
+	} else {
+		target.SendStats()
+	}
+}
+
+// DropGold leaves a pile of gold worth amount on the map
+func (m *WorldMap) DropGold(x, y float64, amount int, players []*Player) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.spawnGoldAt(x, y, amount, players)
+}
+
+// RequestDuel challenges targetID to a duel
+func (g *Game) RequestDuel(p *Player, targetID int) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	target, ok := g.players[targetID]
+	if !ok || target == p || p.Duel != nil || target.Duel != nil {
+		return
+	}
+	if !inRange(p, target, duelRequestRange) {
+		return
+	}
+
+	g.lastDuelID++
+	d := &Duel{ID: g.lastDuelID, From: p, To: target}
+	g.duels[d.ID] = d
+	p.Duel = d
+	target.Duel = d
+
+	target.SendJSON(MsgDuelInvite{
+		Type:   "DUEL_REQUEST",
+		DuelID: d.ID,
+		FromID: p.ID,
+	})
+}
+
+// AcceptDuel starts a duel p was challenged to
+func (g *Game) AcceptDuel(p *Player, duelID int) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	d := p.Duel
+	if d == nil || d.ID != duelID || d.To != p || d.Accepted {
+		return
+	}
+
+	d.Accepted = true
+	for _, dp := range []*Player{d.From, d.To} {
+		dp.SendJSON(MsgDuelStart{
+			Type:       "DUEL_START",
+			DuelID:     d.ID,
+			OpponentID: d.opponent(dp).ID,
+		})
+	}
+}
+
+// CancelDuel declines a pending duel or forfeits a running one
+func (g *Game) CancelDuel(p *Player) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	d := p.Duel
+	if d == nil {
+		return
+	}
+	if d.Accepted {
+		g.endDuel(d, d.opponent(p), "forfeit")
+	} else {
+		g.endDuel(d, nil, "cancelled")
+	}
+}
+
+// endDuel closes a duel. winner may be nil. Caller must hold g.lock.
+func (g *Game) endDuel(d *Duel, winner *Player, reason string) {
+	delete(g.duels, d.ID)
+	if d.From.Duel == d {
+		d.From.Duel = nil
+	}
+	if d.To.Duel == d {
+		d.To.Duel = nil
+	}
+
+	msg := MsgDuelEnd{
+		Type:   "DUEL_END",
+		DuelID: d.ID,
+		Reason: reason,
+	}
+	if winner != nil {
+		msg.WinnerID = winner.ID
+	}
+	d.From.SendJSON(msg)
+	d.To.SendJSON(msg)
+}
+
+// checkDuelDistances ends duels whose players have separated. Caller must hold g.lock.
+func (g *Game) checkDuelDistances() {
+	for _, d := range g.duels {
+		r := duelRange
+		if !d.Accepted {
+			r = duelRequestRange
+		}
+		if !inRange(d.From, d.To, r) {
+			g.endDuel(d, nil, "distance")
+		}
+	}
+}
+
+// handleDeath deals with a player at 0 HP. Losing a duel only ends the duel;
+// a PvP kill credits the killer and costs the victim part of their gold.
+// Caller must hold g.lock.
+func (g *Game) handleDeath(p *Player) {
+	killer := g.players[p.lastAttackerID]
+	p.lastAttackerID = 0
+
+	if d := p.Duel; d != nil && d.Accepted && killer == d.opponent(p) {
+		p.HP = 1
+		p.SendStats()
+		g.endDuel(d, killer, "defeat")
+		return
+	}
+
+	if killer != nil && killer != p {
+		killer.PvPKills++
+		p.PvPDeaths++
+
+		lost := int(float64(p.Gold) * pvpGoldPenalty)
+		if lost > 0 {
+			p.Gold -= lost
+			if m, ok := g.maps[p.MapID]; ok {
+				m.DropGold(p.X, p.Y, lost, g.mapPlayers(p.MapID))
+			}
+		}
+
+		fmt.Printf("Player %d killed player %d on %s\n", killer.ID, p.ID, p.MapID)
+		g.broadcastJSON(MsgPvPKill{
+			Type:     "PVP_KILL",
+			KillerID: killer.ID,
+			VictimID: p.ID,
+			GoldLost: lost,
+		})
+		killer.SendStats()
+	}
+
+	g.respawnPlayer(p)
+}
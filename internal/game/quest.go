@@ -0,0 +1,209 @@
+package game
+
+import "fmt"
+
+type QuestObjectiveType int
+
+const (
+	QuestObjectiveKill QuestObjectiveType = iota
+	QuestObjectiveCollect
+	QuestObjectiveDeliver
+)
+
+// QuestObjective is one step of a quest.
+//   - Kill: defeat Count monsters of MonsterType
+//   - Collect: pick up Count items of ItemType
+//   - Deliver: hand Count items of ItemType to the NPC named NPC
+type QuestObjective struct {
+	Type        QuestObjectiveType
+	Description string
+	MonsterType MonsterType
+	ItemType    ItemType
+	NPC         string
+	Count       int
+}
+
+type QuestReward struct {
+	Gold  int
+	XP    int
+	Items []Item
+}
+
+type QuestDef struct {
+	ID          string
+	Name        string
+	Description string
+	Objectives  []QuestObjective
+	Reward      QuestReward
+	Repeatable  bool
+}
+
+// QuestState is a player's progress on an accepted quest.
+// Progress[i] counts towards Objectives[i].Count.
+type QuestState struct {
+	Quest    *QuestDef
+	Progress []int
+}
+
+func (s *QuestState) done() bool {
+	for i, obj := range s.Quest.Objectives {
+		if s.Progress[i] < obj.Count {
+			return false
+		}
+	}
+	return true
+}
+
+// acceptQuest starts a quest for p. Caller must hold g.lock.
+func (g *Game) acceptQuest(p *Player, questID string) {
+	def, ok := questDefs[questID]
+	if !ok {
+		return
+	}
+	if _, active := p.Quests[questID]; active {
+		return
+	}
+	if p.CompletedQuests[questID] && !def.Repeatable {
+		return
+	}
+
+	state := &QuestState{
+		Quest:    def,
+		Progress: make([]int, len(def.Objectives)),
+	}
+	p.Quests[questID] = state
+	p.sendQuestUpdate(state, false)
+}
+
+// questProgress advances every active objective accepted by match by n.
+func (p *Player) questProgress(n int, match func(obj *QuestObjective) bool) {
+	for _, state := range p.Quests {
+		changed := false
+		for i := range state.Quest.Objectives {
+			obj := &state.Quest.Objectives[i]
+			if state.Progress[i] >= obj.Count || !match(obj) {
+				continue
+			}
+			state.Progress[i] += n
+			if state.Progress[i] > obj.Count {
+				state.Progress[i] = obj.Count
+			}
+			changed = true
+		}
+		if changed {
+			p.checkQuest(state)
+		}
+	}
+}
+
+func (p *Player) onMonsterKilled(mon *Monster) {
+	p.questProgress(1, func(obj *QuestObjective) bool {
+		return obj.Type == QuestObjectiveKill && obj.MonsterType == mon.Type
+	})
+}
+
+func (p *Player) onItemCollected(item *Item) {
+	p.questProgress(1, func(obj *QuestObjective) bool {
+		return obj.Type == QuestObjectiveCollect && obj.ItemType == item.Type
+	})
+}
+
+// onNPCInteract hands over items for any delivery objective addressed to npc
+func (p *Player) onNPCInteract(npc *NPC) {
+	for _, state := range p.Quests {
+		changed := false
+		for i, obj := range state.Quest.Objectives {
+			if obj.Type != QuestObjectiveDeliver || obj.NPC != npc.Name {
+				continue
+			}
+			for state.Progress[i] < obj.Count {
+				idx := p.inventoryIndexOfType(obj.ItemType)
+				if idx == -1 {
+					break
+				}
+				p.Inventory = append(p.Inventory[:idx], p.Inventory[idx+1:]...)
+				state.Progress[i]++
+				changed = true
+			}
+		}
+		if changed {
+			p.SendInventory()
+			p.checkQuest(state)
+		}
+	}
+}
+
+// inventoryIndexOfType finds an item of type t that isn't offered in a trade
+func (p *Player) inventoryIndexOfType(t ItemType) int {
+	for i, it := range p.Inventory {
+		if it.Type == t && !p.offersInTrade(it.ID) {
+			return i
+		}
+	}
+	return -1
+}
+
+// checkQuest completes the quest and hands out rewards once every objective is met
+func (p *Player) checkQuest(state *QuestState) {
+	if !state.done() {
+		p.sendQuestUpdate(state, false)
+		return
+	}
+
+	def := state.Quest
+	delete(p.Quests, def.ID)
+	p.CompletedQuests[def.ID] = true
+
+	p.Gold += def.Reward.Gold
+	p.XP += def.Reward.XP
+	for _, reward := range def.Reward.Items {
+		item := reward
+		if p.game != nil {
+			item.ID = p.game.newItemID()
+		}
+		if !p.addItem(&item) {
+			break
+		}
+	}
+
+	fmt.Printf("Player %d completed quest %s\n", p.ID, def.ID)
+	p.sendQuestUpdate(state, true)
+	if len(def.Reward.Items) > 0 {
+		p.SendInventory()
+	}
+	p.RecalculateStats()
+}
+
+func (p *Player) sendQuestUpdate(state *QuestState, completed bool) {
+	objectives := make([]QuestObjectiveData, 0, len(state.Quest.Objectives))
+	for i, obj := range state.Quest.Objectives {
+		objectives = append(objectives, QuestObjectiveData{
+			Description: obj.Description,
+			Progress:    state.Progress[i],
+			Count:       obj.Count,
+		})
+	}
+
+	p.SendJSON(MsgQuestUpdate{
+		Type:       "QUEST_UPDATE",
+		QuestID:    state.Quest.ID,
+		Name:       state.Quest.Name,
+		Objectives: objectives,
+		Completed:  completed,
+	})
+}
+
+// AbandonQuest drops an active quest and its progress
+func (g *Game) AbandonQuest(p *Player, questID string) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if _, ok := p.Quests[questID]; !ok {
+		return
+	}
+	delete(p.Quests, questID)
+	p.SendJSON(MsgQuestAbandon{
+		Type:    "QUEST_ABANDON",
+		QuestID: questID,
+	})
+}
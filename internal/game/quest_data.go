@@ -0,0 +1,35 @@
+package game
+
+var questDefs = map[string]*QuestDef{
+	"water_hunt": {
+		ID:          "water_hunt",
+		Name:        "Drying Out",
+		Description: "Water monsters are flooding the field. Thin their numbers.",
+		Objectives: []QuestObjective{
+			{Type: QuestObjectiveKill, Description: "Defeat water monsters", MonsterType: MonsterTypeWater, Count: 5},
+		},
+		Reward: QuestReward{Gold: 200, XP: 50},
+	},
+	"gold_rush": {
+		ID:          "gold_rush",
+		Name:        "Gold Rush",
+		Description: "Collect gold dropped by monsters.",
+		Objectives: []QuestObjective{
+			{Type: QuestObjectiveCollect, Description: "Pick up gold", ItemType: ItemTypeGold, Count: 3},
+		},
+		Reward: QuestReward{
+			XP:    30,
+			Items: []Item{{Type: ItemTypeArmor, Name: "Miner's Helmet", Defense: 4}},
+		},
+		Repeatable: true,
+	},
+	"sword_delivery": {
+		ID:          "sword_delivery",
+		Name:        "Spare Blades",
+		Description: "The Shopkeeper is short on stock. Bring him a sword.",
+		Objectives: []QuestObjective{
+			{Type: QuestObjectiveDeliver, Description: "Deliver a sword to the Shopkeeper", ItemType: ItemTypeWeapon, NPC: "Shopkeeper", Count: 1},
+		},
+		Reward: QuestReward{Gold: 150, XP: 20},
+	},
+}
@@ -0,0 +1,143 @@
+package game
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+func newSessionToken() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Disconnected reports whether p lost its connection and is waiting to be
+// resumed. Caller must hold g.lock.
+func (p *Player) Disconnected() bool {
+	return !p.disconnectedAt.IsZero()
+}
+
+// DisconnectPlayer detaches conn from player id. With hold set and a
+// reconnect grace configured, the player stays in the world, frozen and
+// invulnerable, until it is resumed or the grace runs out; otherwise it is
+// removed. Nothing happens if the player has already moved to another
+// connection.
+func (g *Game) DisconnectPlayer(id int, conn Connection, hold bool) {
+	g.inputLock.Lock()
+	defer g.inputLock.Unlock()
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	p, ok := g.players[id]
+	if !ok || p.Conn != conn {
+		return
+	}
+	if !hold || g.cfg.ReconnectGrace <= 0 {
+		g.removePlayer(p)
+		return
+	}
+
+	g.leaveActivities(p)
+	g.closeDialog(p)
+	p.Conn = nil
+	p.disconnectedAt = time.Now()
+	fmt.Printf("Player %d disconnected, holding for %v\n", p.ID, g.cfg.ReconnectGrace)
+}
+
+// ResumePlayer moves the connection of fresh, a player that just joined, to
+// the player holding token and removes fresh. The resumed player gets the
+// full game state again. If its old connection is still open, it is closed.
+// ok is false if no player has the token; fresh is then left alone.
+func (g *Game) ResumePlayer(fresh *Player, token string) (p *Player, ok bool) {
+	g.inputLock.Lock()
+	defer g.inputLock.Unlock()
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if token != "" {
+		for _, other := range g.players {
+			if other != fresh && other.token == token {
+				p = other
+				break
+			}
+		}
+	}
+	if p == nil {
+		fresh.SendJSON(MsgResumeFailed{Type: "RESUME_FAILED"})
+		return fresh, false
+	}
+
+	if old := p.Conn; old != nil {
+		// Closing may block on the network
+		go old.Close()
+	}
+	p.Conn = fresh.Conn
+	p.Protocol = fresh.Protocol
+	p.disconnectedAt = time.Time{}
+	fresh.Conn = nil
+	g.removePlayer(fresh)
+
+	fmt.Printf("Player %d resumed\n", p.ID)
+	g.sendState(p)
+	return p, true
+}
+
+// sendState sends p everything a client needs to draw the game from
+// scratch. Caller must hold g.lock.
+func (g *Game) sendState(p *Player) {
+	p.SendInventory()
+	p.SendEquipment()
+	p.SendHotbar()
+	p.SendSkills()
+
+	p.SendJSON(MsgWelcome{
+		Type:    "WELCOME",
+		ID:      p.ID,
+		HP:      p.HP,
+		MaxHP:   p.MaxHP,
+		Attack:  p.Attack,
+		Defense: p.Defense,
+		Speed:   p.Speed,
+		Gold:    p.Gold,
+		XP:      p.XP,
+		Mana:    p.Mana,
+		MaxMana: p.MaxMana,
+		Buffs:   p.Buffs,
+
+		AutoAttack: p.AutoAttack,
+		Token:      p.token,
+	})
+
+	// Send initial map info (portals, walls, NPCs)
+	if m, ok := g.maps[p.MapID]; ok {
+		p.SendJSON(mapSwitchMsg(m, p.MapID, p.X, p.Y))
+
+		for _, item := range m.Items {
+			p.SendJSON(MsgItemSpawn{
+				Type:     "ITEM_SPAWN",
+				ID:       item.ID,
+				ItemType: int(item.Type),
+				X:        item.X,
+				Y:        item.Y,
+			})
+		}
+	}
+
+	var marketItems []*MarketItem
+	for _, it := range g.market {
+		marketItems = append(marketItems, it)
+	}
+	p.SendJSON(MsgMarketUpdate{
+		Type:  "MARKET_UPDATE",
+		Items: marketItems,
+	})
+
+	for _, state := range p.Quests {
+		p.sendQuestUpdate(state, false)
+	}
+	if p.Party != nil {
+		g.sendPartyUpdate(p.Party)
+	}
+}
@@ -0,0 +1,238 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SavedPlayer is the persistent part of a player
+type SavedPlayer struct {
+	ID              int                  `json:"id"`
+	MapID           string               `json:"map_id"`
+	X               float64              `json:"x"`
+	Y               float64              `json:"y"`
+	HP              int                  `json:"hp"`
+	Mana            int                  `json:"mana"`
+	Gold            int                  `json:"gold"`
+	XP              int                  `json:"xp"`
+	Inventory       []*Item              `json:"inventory"`
+	Equipment       [5]*Item             `json:"equipment"`
+	Hotbar          []string             `json:"hotbar"`
+	Quests          map[string][]int     `json:"quests"` // quest ID -> objective progress
+	CompletedQuests map[string]bool      `json:"completed_quests"`
+	Buffs           []*Buff              `json:"buffs"`
+	Cooldowns       map[string]time.Time `json:"cooldowns"`
+	PvPKills        int                  `json:"pvp_kills"`
+	PvPDeaths       int                  `json:"pvp_deaths"`
+	Token           string               `json:"token"`
+}
+
+// SavedState is what SaveState writes to disk
+type SavedState struct {
+	SavedAt      time.Time      `json:"saved_at"`
+	Players      []*SavedPlayer `json:"players"`
+	Market       []*MarketItem  `json:"market"`
+	LastMarketID int            `json:"last_market_id"`
+	LastPlayerID int            `json:"last_player_id"`
+}
+
+// Stop ends the game loop and waits for the current tick to finish. A
+// game stopped before Start never runs. It is safe to call more than once.
+func (g *Game) Stop() {
+	g.lock.Lock()
+	if !g.stopped {
+		g.stopped = true
+		close(g.quitch)
+	}
+	started := g.started
+	g.lock.Unlock()
+
+	if started {
+		<-g.donech
+	}
+}
+
+// AnnounceShutdown tells every player the server goes down in seconds
+func (g *Game) AnnounceShutdown(seconds int) {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+
+	g.broadcastJSON(MsgServerShutdown{
+		Type:    "SERVER_SHUTDOWN",
+		Seconds: seconds,
+	})
+}
+
+// SaveState writes players and market listings to path as JSON. The file is
+// replaced atomically so a crash mid-write keeps the previous save.
+func (g *Game) SaveState(path string) error {
+	g.lock.RLock()
+	state := SavedState{
+		SavedAt:      time.Now(),
+		Players:      make([]*SavedPlayer, 0, len(g.players)),
+		Market:       make([]*MarketItem, 0, len(g.market)),
+		LastMarketID: g.lastMarketID,
+		LastPlayerID: g.lastID,
+	}
+	for _, p := range g.players {
+		quests := make(map[string][]int, len(p.Quests))
+		for id, qs := range p.Quests {
+			quests[id] = qs.Progress
+		}
+		state.Players = append(state.Players, &SavedPlayer{
+			ID:              p.ID,
+			MapID:           p.MapID,
+			X:               p.X,
+			Y:               p.Y,
+			HP:              p.HP,
+			Mana:            p.Mana,
+			Gold:            p.Gold,
+			XP:              p.XP,
+			Inventory:       p.Inventory,
+			Equipment:       p.Equipment,
+			Hotbar:          p.Hotbar[:],
+			Quests:          quests,
+			CompletedQuests: p.CompletedQuests,
+			Buffs:           p.Buffs,
+			Cooldowns:       p.Cooldowns,
+			PvPKills:        p.PvPKills,
+			PvPDeaths:       p.PvPDeaths,
+			Token:           p.token,
+		})
+	}
+	for _, it := range g.market {
+		state.Market = append(state.Market, it)
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	g.lock.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+
+	fmt.Printf("Saved %d players and %d market listings to %s\n", len(state.Players), len(state.Market), path)
+	return nil
+}
+
+// LoadState restores players and market listings from a file written by
+// SaveState. Players come back as if they had just dropped: held for the
+// reconnect grace period, waiting for their client to RESUME with its
+// token. Player IDs continue after the saved ones, so a new player never
+// takes a seller's ID and with it their listings and sales. A missing file
+// is not an error.
+func (g *Game) LoadState(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var state SavedState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if g.cfg.ReconnectGrace > 0 {
+		now := time.Now()
+		for _, sp := range state.Players {
+			if sp.Token != "" {
+				g.players[sp.ID] = g.restorePlayer(sp, now)
+			}
+		}
+	}
+
+	for _, it := range state.Market {
+		g.market[it.ID] = it
+		if it.ID > g.lastMarketID {
+			g.lastMarketID = it.ID
+		}
+	}
+	if state.LastMarketID > g.lastMarketID {
+		g.lastMarketID = state.LastMarketID
+	}
+
+	// Saves from before last_player_id only have the IDs in use
+	lastID := state.LastPlayerID
+	for _, sp := range state.Players {
+		lastID = max(lastID, sp.ID)
+	}
+	for _, it := range state.Market {
+		lastID = max(lastID, it.SellerID)
+	}
+	g.lastID = max(g.lastID, lastID)
+	return nil
+}
+
+// restorePlayer rebuilds a saved player as disconnected at now. Players
+// saved on a channel or instance that no longer exists start over in town,
+// and quests that are no longer defined are dropped. Buffs and cooldowns
+// keep their wall clock expiry, so downtime counts against them.
+// Caller must hold g.lock.
+func (g *Game) restorePlayer(sp *SavedPlayer, now time.Time) *Player {
+	p := NewPlayer(sp.ID, nil, g)
+	p.token = sp.Token
+	p.disconnectedAt = now
+
+	if _, ok := g.maps[sp.MapID]; ok {
+		p.MapID, p.X, p.Y = sp.MapID, sp.X, sp.Y
+	}
+	if m, ok := g.maps[p.MapID]; ok {
+		p.MapID = g.assignChannel(p, m).ID
+	}
+
+	if sp.Inventory != nil {
+		p.Inventory = sp.Inventory
+	}
+	p.Equipment = sp.Equipment
+	copy(p.Hotbar[:], sp.Hotbar)
+	for id, progress := range sp.Quests {
+		def, ok := questDefs[id]
+		if !ok {
+			continue
+		}
+		state := &QuestState{
+			Quest:    def,
+			Progress: make([]int, len(def.Objectives)),
+		}
+		copy(state.Progress, progress)
+		p.Quests[id] = state
+	}
+	if sp.CompletedQuests != nil {
+		p.CompletedQuests = sp.CompletedQuests
+	}
+	p.Buffs = sp.Buffs
+	if sp.Cooldowns != nil {
+		p.Cooldowns = sp.Cooldowns
+	}
+	p.Gold = sp.Gold
+	p.XP = sp.XP
+	p.PvPKills = sp.PvPKills
+	p.PvPDeaths = sp.PvPDeaths
+
+	p.RecalculateStats()
+	if sp.HP > 0 && sp.HP < p.MaxHP {
+		p.HP = sp.HP
+	}
+	if sp.Mana > 0 && sp.Mana < p.MaxMana {
+		p.Mana = sp.Mana
+	}
+	return p
+}
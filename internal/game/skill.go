@@ -0,0 +1,227 @@
+package game
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	manaRegenAmount   = 5
+	manaRegenInterval = time.Second
+)
+
+type TargetMode int
+
+const (
+	TargetDirection TargetMode = iota // aimed with dir_x/dir_y, falls back to facing
+	TargetMonster                     // needs a monster target_id within Range
+	TargetSelf                        // centered on the caster
+)
+
+// SkillDef describes an ability. A skill fires Projectiles projectiles fanned
+// Spread radians apart and/or hits every monster within AoERadius of its
+// center (the caster, or the target for TargetMonster). Power scales the
+// caster's attack for both.
+type SkillDef struct {
+	ID          string
+	Name        string
+	Cooldown    time.Duration
+	ManaCost    int
+	Targeting   TargetMode
+	Range       float64
+	Projectile  ProjectileType
+	Projectiles int
+	Spread      float64
+	AoERadius   float64
+	Power       float64
+}
+
+var skillDefs = map[string]*SkillDef{
+	"shot": {
+		ID: "shot", Name: "Shot", Cooldown: 500 * time.Millisecond,
+		Targeting: TargetDirection, Projectiles: 1, Power: 1,
+	},
+	"fan": {
+		ID: "fan", Name: "Fan of Knives", Cooldown: 3 * time.Second, ManaCost: 15,
+		Targeting: TargetDirection, Projectiles: 5, Spread: 0.25, Power: 0.8,
+	},
+	"fireball": {
+		ID: "fireball", Name: "Fireball", Cooldown: 4 * time.Second, ManaCost: 20,
+		Targeting: TargetMonster, Range: 400, Projectile: ProjectileTypeFire, Projectiles: 1, Power: 2,
+	},
+	"frost_burst": {
+		ID: "frost_burst", Name: "Frost Burst", Cooldown: 6 * time.Second, ManaCost: 25,
+		Targeting: TargetMonster, Range: 300, Projectile: ProjectileTypeWater, AoERadius: 80, Power: 1.2,
+	},
+	"nova": {
+		ID: "nova", Name: "Nova", Cooldown: 8 * time.Second, ManaCost: 30,
+		Targeting: TargetSelf, AoERadius: 120, Power: 1.5,
+	},
+}
+
+// skillOrder is the order skills are listed to clients
+var skillOrder = []string{"shot", "fan", "fireball", "frost_burst", "nova"}
+
+// Cast uses one of p's skills. targetID is used by TargetMonster skills,
+// (dirX, dirY) by TargetDirection skills.
+func (g *Game) Cast(p *Player, skillID string, targetID int, dirX, dirY float64) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	def, ok := skillDefs[skillID]
+	if !ok || p.HP <= 0 {
+		return
+	}
+
+	now := time.Now()
+	cooldownKey := "skill:" + def.ID
+	if now.Before(p.Cooldowns[cooldownKey]) || p.Mana < def.ManaCost {
+		return
+	}
+
+	m, ok := g.maps[p.MapID]
+	if !ok {
+		return
+	}
+	if !m.castSkill(p, def, targetID, dirX, dirY, g.mapPlayers(p.MapID)) {
+		return
+	}
+
+	p.Mana -= def.ManaCost
+	p.Cooldowns[cooldownKey] = now.Add(def.Cooldown)
+	p.SendJSON(MsgCooldown{
+		Type:  "COOLDOWN",
+		Group: cooldownKey,
+		Until: p.Cooldowns[cooldownKey],
+	})
+	p.SendStats()
+}
+
+// SetAutoAttack turns automatic shooting at the nearest monster on or off
+func (p *Player) SetAutoAttack(enabled bool) {
+	p.AutoAttack = enabled
+	p.SendStats()
+}
+
+// regenMana restores mana in fixed steps and reports changes to the player
+func (p *Player) regenMana(now time.Time) {
+	if p.Mana >= p.MaxMana {
+		p.lastManaRegen = now
+		return
+	}
+	if now.Sub(p.lastManaRegen) < manaRegenInterval {
+		return
+	}
+
+	p.lastManaRegen = now
+	p.Mana += manaRegenAmount
+	if p.Mana > p.MaxMana {
+		p.Mana = p.MaxMana
+	}
+	p.SendStats()
+}
+
+func (p *Player) SendSkills() {
+	skills := make([]SkillData, 0, len(skillOrder))
+	for _, id := range skillOrder {
+		def := skillDefs[id]
+		skills = append(skills, SkillData{
+			ID:         def.ID,
+			Name:       def.Name,
+			CooldownMs: def.Cooldown.Milliseconds(),
+			ManaCost:   def.ManaCost,
+			Targeting:  int(def.Targeting),
+		})
+	}
+	p.SendJSON(MsgSkills{
+		Type:   "SKILLS",
+		Skills: skills,
+	})
+}
+
+// castSkill resolves targeting and applies the skill. It reports false if
+// the skill could not be cast, in which case no cost should be paid.
+func (m *WorldMap) castSkill(p *Player, def *SkillDef, targetID int, dirX, dirY float64, players []*Player) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	cx, cy := p.X, p.Y
+	vx, vy := dirX, dirY
+
+	switch def.Targeting {
+	case TargetMonster:
+		mon, ok := m.Monsters[targetID]
+		if !ok {
+			return false
+		}
+		dx := mon.X - p.X
+		dy := mon.Y - p.Y
+		if def.Range > 0 && dx*dx+dy*dy > def.Range*def.Range {
+			return false
+		}
+		vx, vy = dx, dy
+		cx, cy = mon.X, mon.Y
+	case TargetDirection:
+		if vx == 0 && vy == 0 {
+			vx, vy = p.DirX, p.DirY
+		}
+	}
+
+	if def.Projectiles > 0 {
+		l := math.Sqrt(vx*vx + vy*vy)
+		if l == 0 {
+			return false
+		}
+		types := make([]ProjectileType, def.Projectiles)
+		for i := range types {
+			types[i] = def.Projectile
+		}
+		m.fireSpread(p, vx/l, vy/l, types, def.Spread, def.Power)
+	}
+
+	if def.AoERadius > 0 {
+		m.damageArea(p, cx, cy, def, players)
+	}
+	return true
+}
+
+// damageArea hits every monster, and every player the caster may damage,
+// within the skill's radius. Caller must hold m.lock.
+func (m *WorldMap) damageArea(p *Player, cx, cy float64, def *SkillDef, players []*Player) {
+	attack := int(float64(p.Attack) * def.Power)
+
+	for _, mon := range m.Monsters {
+		if mon.HP <= 0 {
+			continue
+		}
+		dx := mon.X - cx
+		dy := mon.Y - cy
+		if dx*dx+dy*dy > def.AoERadius*def.AoERadius {
+			continue
+		}
+
+		m.damageMonster(mon, p, p.ID, attack, def.Projectile, players)
+	}
+
+	for _, target := range players {
+		if !m.canDamage(p, target) {
+			continue
+		}
+		dx := target.X - cx
+		dy := target.Y - cy
+		if dx*dx+dy*dy <= def.AoERadius*def.AoERadius {
+			m.hitPlayer(p, target, attack, def.Projectile)
+		}
+	}
+}
+
+// mapPlayers returns the players currently on mapID. Caller must hold g.lock.
+func (g *Game) mapPlayers(mapID string) []*Player {
+	var players []*Player
+	for _, p := range g.players {
+		if p.MapID == mapID {
+			players = append(players, p)
+		}
+	}
+	return players
+}
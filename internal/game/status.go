@@ -0,0 +1,191 @@
+package game
+
+import "time"
+
+type StatusType int
+
+const (
+	StatusBurn StatusType = iota
+	StatusSlow
+	StatusRoot
+)
+
+// StackRule decides what happens when an effect is applied to a target that
+// already has it.
+type StackRule int
+
+const (
+	StackRefresh StackRule = iota // reset the duration
+	StackAdd                      // add a stack up to MaxStacks and reset the duration
+	StackIgnore                   // keep the running effect untouched
+)
+
+type StatusDef struct {
+	Duration     time.Duration
+	Stacking     StackRule
+	MaxStacks    int
+	TickInterval time.Duration
+	TickDamage   int     // per stack, per tick
+	SpeedFactor  float64 // movement multiplier while active, 1 for no change
+}
+
+var statusDefs = map[StatusType]*StatusDef{
+	StatusBurn: {Duration: 3 * time.Second, Stacking: StackAdd, MaxStacks: 3, TickInterval: 500 * time.Millisecond, TickDamage: 3, SpeedFactor: 1},
+	StatusSlow: {Duration: 2 * time.Second, Stacking: StackRefresh, MaxStacks: 1, SpeedFactor: 0.5},
+	StatusRoot: {Duration: time.Second, Stacking: StackIgnore, MaxStacks: 1, SpeedFactor: 0},
+}
+
+// elementStatus is the effect each projectile element leaves on its target
+var elementStatus = map[ProjectileType]StatusType{
+	ProjectileTypeFire:  StatusBurn,
+	ProjectileTypeWater: StatusSlow,
+	ProjectileTypeGrass: StatusRoot,
+}
+
+type StatusEffect struct {
+	Type      StatusType
+	SourceID  int // player who applied it, 0 if none
+	Stacks    int
+	ExpiresAt time.Time
+	nextTick  time.Time
+}
+
+// StatusEffects is the list of effects active on a monster or player
+type StatusEffects []*StatusEffect
+
+// Apply adds an effect following its stacking rule
+func (s *StatusEffects) Apply(t StatusType, sourceID int, now time.Time) {
+	def, ok := statusDefs[t]
+	if !ok {
+		return
+	}
+
+	for _, e := range *s {
+		if e.Type != t {
+			continue
+		}
+		switch def.Stacking {
+		case StackIgnore:
+			return
+		case StackAdd:
+			if e.Stacks < def.MaxStacks {
+				e.Stacks++
+			}
+		}
+		e.SourceID = sourceID
+		e.ExpiresAt = now.Add(def.Duration)
+		return
+	}
+
+	*s = append(*s, &StatusEffect{
+		Type:      t,
+		SourceID:  sourceID,
+		Stacks:    1,
+		ExpiresAt: now.Add(def.Duration),
+		nextTick:  now.Add(def.TickInterval),
+	})
+}
+
+// Tick removes expired effects and returns the damage dealt since the last
+// tick, the player credited with it, and whether the effect list changed.
+func (s *StatusEffects) Tick(now time.Time) (damage int, sourceID int, changed bool) {
+	kept := (*s)[:0]
+	for _, e := range *s {
+		def := statusDefs[e.Type]
+		if def.TickDamage > 0 {
+			for !e.nextTick.After(now) && e.nextTick.Before(e.ExpiresAt) {
+				damage += def.TickDamage * e.Stacks
+				sourceID = e.SourceID
+				e.nextTick = e.nextTick.Add(def.TickInterval)
+			}
+		}
+
+		if now.Before(e.ExpiresAt) {
+			kept = append(kept, e)
+		} else {
+			changed = true
+		}
+	}
+	*s = kept
+	return damage, sourceID, changed
+}
+
+// SpeedFactor is the combined movement multiplier of all active effects
+func (s StatusEffects) SpeedFactor() float64 {
+	factor := 1.0
+	for _, e := range s {
+		factor *= statusDefs[e.Type].SpeedFactor
+	}
+	return factor
+}
+
+func (s StatusEffects) Has(t StatusType) bool {
+	for _, e := range s {
+		if e.Type == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Types lists the active effect types for snapshots
+func (s StatusEffects) Types() []int {
+	if len(s) == 0 {
+		return nil
+	}
+	types := make([]int, 0, len(s))
+	for _, e := range s {
+		types = append(types, int(e.Type))
+	}
+	return types
+}
+
+// ApplyStatus puts an effect on the player and updates movement stats
+func (p *Player) ApplyStatus(t StatusType, sourceID int) {
+	p.Effects.Apply(t, sourceID, p.clock())
+	p.RecalculateStats()
+}
+
+// UpdateStatusEffects ticks effects on monsters and players. Damage over time
+// can kill monsters, credited to whoever applied the effect, but never takes a
+// player below 1 HP.
+func (m *WorldMap) UpdateStatusEffects(players []*Player, now time.Time) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.now = now
+
+	playerMap := make(map[int]*Player)
+	for _, p := range players {
+		playerMap[p.ID] = p
+	}
+
+	for _, mon := range m.Monsters {
+		damage, sourceID, _ := mon.Effects.Tick(now)
+		if damage == 0 || mon.HP <= 0 {
+			continue
+		}
+
+		mon.HP -= damage
+		m.addDamageEvent(DamageTargetMonster, mon.ID, sourceID, mon.X, mon.Y, DamageRoll{Amount: damage, Effectiveness: 1})
+		if mon.HP <= 0 {
+			m.killMonster(mon, playerMap[sourceID], players)
+		}
+	}
+
+	for _, p := range players {
+		damage, _, changed := p.Effects.Tick(now)
+		if damage > 0 && p.HP > 0 {
+			p.HP -= damage
+			if p.HP < 1 {
+				p.HP = 1
+			}
+			m.addDamageEvent(DamageTargetPlayer, p.ID, 0, p.X, p.Y, DamageRoll{Amount: damage, Effectiveness: 1})
+		}
+		if changed {
+			p.RecalculateStats()
+		} else if damage > 0 {
+			p.SendStats()
+		}
+	}
+}
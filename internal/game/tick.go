@@ -0,0 +1,95 @@
+package game
+
+import (
+	"fmt"
+	"time"
+)
+
+// maxCatchUpTicks is how many missed ticks are replayed back to back after an
+// overrun. Anything beyond that is dropped so a long stall doesn't turn into a
+// burst of fast-forwarded simulation.
+const maxCatchUpTicks = 5
+
+// TickStats are timing metrics of the game loop
+type TickStats struct {
+	Ticks    int64
+	Overruns int64 // ticks that took longer than the tick interval
+	Dropped  int64 // ticks skipped because catch-up fell too far behind
+	Last     time.Duration
+	Max      time.Duration
+	Avg      time.Duration // exponential moving average
+}
+
+// SetTickRate sets the simulation rate in ticks per second. Call it before Start.
+func (g *Game) SetTickRate(rate int) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if rate <= 0 {
+		rate = DefaultConfig().TickRate
+	}
+	g.cfg.TickRate = rate
+	g.tickInterval = time.Second / time.Duration(rate)
+}
+
+// TickInterval is the simulated time advanced by each Update
+func (g *Game) TickInterval() time.Duration {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+
+	return g.tickInterval
+}
+
+func (g *Game) TickStats() TickStats {
+	g.statsLock.Lock()
+	defer g.statsLock.Unlock()
+
+	return g.stats
+}
+
+// runTicks runs every tick due by now, starting at *next, and advances *next
+// past them.
+func (g *Game) runTicks(next *time.Time, interval time.Duration) {
+	now := time.Now()
+	for steps := 0; !now.Before(*next); steps++ {
+		if steps == maxCatchUpTicks {
+			dropped := int64(now.Sub(*next)/interval) + 1
+			*next = next.Add(time.Duration(dropped) * interval)
+			g.recordDropped(dropped)
+			fmt.Printf("Game loop fell behind, dropped %d ticks\n", dropped)
+			return
+		}
+
+		start := time.Now()
+		g.Update()
+		g.recordTick(time.Since(start), interval)
+		*next = next.Add(interval)
+	}
+}
+
+func (g *Game) recordTick(d, interval time.Duration) {
+	g.statsLock.Lock()
+	defer g.statsLock.Unlock()
+
+	s := &g.stats
+	s.Ticks++
+	s.Last = d
+	if d > s.Max {
+		s.Max = d
+	}
+	if d > interval {
+		s.Overruns++
+	}
+	if s.Avg == 0 {
+		s.Avg = d
+	} else {
+		s.Avg += (d - s.Avg) / 16
+	}
+}
+
+func (g *Game) recordDropped(n int64) {
+	g.statsLock.Lock()
+	defer g.statsLock.Unlock()
+
+	g.stats.Dropped += n
+}
@@ -0,0 +1,340 @@
+package game
+
+import "fmt"
+
+const tradeRange = 150.0
+
+// TradeOffer is what one side of a trade puts on the table.
+// Offered items stay in the owner's inventory until the swap happens.
+type TradeOffer struct {
+	ItemIDs   []int
+	Gold      int
+	Confirmed bool
+}
+
+// Trade is a two-party trade session. From requested it, To has to accept
+// before either side can change their offer.
+type Trade struct {
+	ID       int
+	From     *Player
+	To       *Player
+	Accepted bool
+	Offers   map[int]*TradeOffer
+}
+
+func (t *Trade) partner(p *Player) *Player {
+	if t.From == p {
+		return t.To
+	}
+	return t.From
+}
+
+func (t *Trade) resetConfirmations() {
+	for _, o := range t.Offers {
+		o.Confirmed = false
+	}
+}
+
+// offersInTrade reports whether p has put item itemID on the table in its
+// open trade
+func (p *Player) offersInTrade(itemID int) bool {
+	if p.Trade == nil {
+		return false
+	}
+	offer, ok := p.Trade.Offers[p.ID]
+	if !ok {
+		return false
+	}
+	for _, id := range offer.ItemIDs {
+		if id == itemID {
+			return true
+		}
+	}
+	return false
+}
+
+func inTradeRange(a, b *Player) bool {
+	if a.MapID != b.MapID {
+		return false
+	}
+	dx := a.X - b.X
+	dy := a.Y - b.Y
+	return dx*dx+dy*dy < tradeRange*tradeRange
+}
+
+// RequestTrade opens a trade session between p and targetID and invites the target
+func (g *Game) RequestTrade(p *Player, targetID int) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	target, ok := g.players[targetID]
+	if !ok || target == p {
+		return
+	}
+	if p.Trade != nil || target.Trade != nil {
+		return
+	}
+	if !inTradeRange(p, target) {
+		return
+	}
+
+	g.lastTradeID++
+	t := &Trade{
+		ID:   g.lastTradeID,
+		From: p,
+		To:   target,
+		Offers: map[int]*TradeOffer{
+			p.ID:      {},
+			target.ID: {},
+		},
+	}
+	g.trades[t.ID] = t
+	p.Trade = t
+	target.Trade = t
+
+	target.SendJSON(MsgTradeInvite{
+		Type:    "TRADE_REQUEST",
+		TradeID: t.ID,
+		FromID:  p.ID,
+	})
+}
+
+// AcceptTrade accepts a pending trade request addressed to p
+func (g *Game) AcceptTrade(p *Player, tradeID int) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	t := p.Trade
+	if t == nil || t.ID != tradeID || t.To != p || t.Accepted {
+		return
+	}
+
+	t.Accepted = true
+	g.sendTradeUpdate(t)
+}
+
+// TradeAddItem puts an inventory item into p's side of the trade
+func (g *Game) TradeAddItem(p *Player, itemID int) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	t := p.Trade
+	if t == nil || !t.Accepted {
+		return
+	}
+	if p.inventoryIndex(itemID) == -1 {
+		return
+	}
+
+	offer := t.Offers[p.ID]
+	for _, id := range offer.ItemIDs {
+		if id == itemID {
+			return
+		}
+	}
+	offer.ItemIDs = append(offer.ItemIDs, itemID)
+
+	t.resetConfirmations()
+	g.sendTradeUpdate(t)
+}
+
+// TradeRemoveItem takes an item back out of p's side of the trade
+func (g *Game) TradeRemoveItem(p *Player, itemID int) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	t := p.Trade
+	if t == nil || !t.Accepted {
+		return
+	}
+
+	offer := t.Offers[p.ID]
+	for i, id := range offer.ItemIDs {
+		if id == itemID {
+			offer.ItemIDs = append(offer.ItemIDs[:i], offer.ItemIDs[i+1:]...)
+			t.resetConfirmations()
+			g.sendTradeUpdate(t)
+			return
+		}
+	}
+}
+
+// TradeSetGold sets the amount of gold p is offering
+func (g *Game) TradeSetGold(p *Player, amount int) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	t := p.Trade
+	if t == nil || !t.Accepted {
+		return
+	}
+	if amount < 0 || amount > p.Gold {
+		return
+	}
+
+	t.Offers[p.ID].Gold = amount
+	t.resetConfirmations()
+	g.sendTradeUpdate(t)
+}
+
+// ConfirmTrade locks in p's side. Once both sides confirm the swap is executed.
+func (g *Game) ConfirmTrade(p *Player) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	t := p.Trade
+	if t == nil || !t.Accepted {
+		return
+	}
+
+	t.Offers[p.ID].Confirmed = true
+	for _, o := range t.Offers {
+		if !o.Confirmed {
+			g.sendTradeUpdate(t)
+			return
+		}
+	}
+
+	g.completeTrade(t)
+}
+
+// CancelTrade aborts p's current trade or pending request
+func (g *Game) CancelTrade(p *Player) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if p.Trade != nil {
+		g.cancelTrade(p.Trade, "cancelled")
+	}
+}
+
+// checkTradeDistances cancels trades whose players have moved apart.
+// Caller must hold g.lock.
+func (g *Game) checkTradeDistances() {
+	for _, t := range g.trades {
+		if !inTradeRange(t.From, t.To) {
+			g.cancelTrade(t, "distance")
+		}
+	}
+}
+
+// cancelTrade closes the session without moving anything. Caller must hold g.lock.
+func (g *Game) cancelTrade(t *Trade, reason string) {
+	g.closeTrade(t)
+
+	msg := MsgTradeClosed{
+		Type:    "TRADE_CLOSED",
+		TradeID: t.ID,
+		Reason:  reason,
+	}
+	t.From.SendJSON(msg)
+	t.To.SendJSON(msg)
+}
+
+func (g *Game) closeTrade(t *Trade) {
+	delete(g.trades, t.ID)
+	if t.From.Trade == t {
+		t.From.Trade = nil
+	}
+	if t.To.Trade == t {
+		t.To.Trade = nil
+	}
+}
+
+// completeTrade validates both offers against the players' current state and
+// swaps them. Nothing is moved unless every check passes. Caller must hold g.lock.
+func (g *Game) completeTrade(t *Trade) {
+	a, b := t.From, t.To
+	offerA, offerB := t.Offers[a.ID], t.Offers[b.ID]
+
+	if !inTradeRange(a, b) {
+		g.cancelTrade(t, "distance")
+		return
+	}
+
+	itemsA, okA := takeTradeItems(a, offerA)
+	itemsB, okB := takeTradeItems(b, offerB)
+	if !okA || !okB || a.Gold < offerA.Gold || b.Gold < offerB.Gold {
+		g.cancelTrade(t, "invalid")
+		return
+	}
+	if len(a.Inventory)-len(itemsA)+len(itemsB) > a.inventorySize() ||
+		len(b.Inventory)-len(itemsB)+len(itemsA) > b.inventorySize() {
+		g.cancelTrade(t, "inventory_full")
+		return
+	}
+
+	a.Inventory = removeTradeItems(a.Inventory, itemsA)
+	b.Inventory = removeTradeItems(b.Inventory, itemsB)
+	a.Inventory = append(a.Inventory, itemsB...)
+	b.Inventory = append(b.Inventory, itemsA...)
+
+	a.Gold += offerB.Gold - offerA.Gold
+	b.Gold += offerA.Gold - offerB.Gold
+
+	g.closeTrade(t)
+	fmt.Printf("Trade %d completed: %d <-> %d\n", t.ID, a.ID, b.ID)
+
+	for _, p := range []*Player{a, b} {
+		p.SendInventory()
+		p.SendJSON(MsgGoldUpdate{
+			Type:   "GOLD_UPDATE",
+			Amount: p.Gold,
+		})
+		p.SendJSON(MsgTradeClosed{
+			Type:    "TRADE_CLOSED",
+			TradeID: t.ID,
+			Reason:  "completed",
+		})
+	}
+}
+
+// takeTradeItems resolves an offer's item IDs against p's inventory.
+// It reports false if any offered item is no longer there.
+func takeTradeItems(p *Player, offer *TradeOffer) ([]*Item, bool) {
+	items := make([]*Item, 0, len(offer.ItemIDs))
+	for _, id := range offer.ItemIDs {
+		idx := p.inventoryIndex(id)
+		if idx == -1 {
+			return nil, false
+		}
+		items = append(items, p.Inventory[idx])
+	}
+	return items, true
+}
+
+func removeTradeItems(inv []*Item, items []*Item) []*Item {
+	remove := make(map[*Item]bool, len(items))
+	for _, it := range items {
+		remove[it] = true
+	}
+
+	kept := make([]*Item, 0, len(inv))
+	for _, it := range inv {
+		if !remove[it] {
+			kept = append(kept, it)
+		}
+	}
+	return kept
+}
+
+func (g *Game) sendTradeUpdate(t *Trade) {
+	for _, p := range []*Player{t.From, t.To} {
+		other := t.partner(p)
+		mine, theirs := t.Offers[p.ID], t.Offers[other.ID]
+		mineItems, _ := takeTradeItems(p, mine)
+		theirItems, _ := takeTradeItems(other, theirs)
+
+		p.SendJSON(MsgTradeUpdate{
+			Type:           "TRADE_UPDATE",
+			TradeID:        t.ID,
+			PartnerID:      other.ID,
+			MyItems:        mineItems,
+			MyGold:         mine.Gold,
+			MyConfirmed:    mine.Confirmed,
+			TheirItems:     theirItems,
+			TheirGold:      theirs.Gold,
+			TheirConfirmed: theirs.Confirmed,
+		})
+	}
+}
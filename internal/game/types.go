@@ -8,6 +8,7 @@ const (
 	ItemTypeGold ItemType = iota
 	ItemTypeWeapon
 	ItemTypeArmor
+	ItemTypeConsumable
 )
 
 type ProjectileType int
@@ -34,6 +35,10 @@ type Item struct {
 
 	// Special
 	ProjectileType ProjectileType
+
+	// Consumables
+	ConsumableID string
+	Count        int
 }
 
 type MonsterType int
@@ -45,12 +50,20 @@ const (
 )
 
 type Monster struct {
-	ID    int
-	X     float64
-	Y     float64
-	Type  MonsterType
-	HP    int
-	MaxHP int
+	ID     int
+	X      float64
+	Y      float64
+	Type   MonsterType
+	HP     int
+	MaxHP  int
+	Attack int
+
+	LastAttack time.Time
+	Effects    StatusEffects
+
+	path     []Waypoint
+	pathGoal int
+	pathAt   time.Time
 }
 
 type Projectile struct {
@@ -61,6 +74,14 @@ type Projectile struct {
 	VX      float64
 	VY      float64
 	Type    ProjectileType
+	Power   float64 // damage multiplier on the owner's attack, 0 means 1
+
+	SpawnedAt  time.Time
+	Traveled   float64
+	PierceLeft int
+
+	hitMonsters map[int]bool
+	hitPlayers  map[int]bool
 }
 
 type NPCType int
@@ -68,6 +89,7 @@ type NPCType int
 const (
 	NPCTypeShop NPCType = iota
 	NPCTypeMarket
+	NPCTypeTalk
 )
 
 type NPC struct {
@@ -76,6 +98,9 @@ type NPC struct {
 	Y    float64
 	Type NPCType
 	Name string
+
+	Dialog *Dialog
+	Shop   *Shop
 }
 
 type MarketItem struct {
@@ -0,0 +1,75 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Validate methods reject malformed client messages before they reach the
+// game. They check only what the message alone can tell; rules that depend
+// on game state stay in the game methods.
+
+func checkSlot(slot, n int) error {
+	if slot < 0 || slot >= n {
+		return fmt.Errorf("slot %d out of range [0, %d)", slot, n)
+	}
+	return nil
+}
+
+func (m *MsgEquip) Validate() error {
+	return checkSlot(m.Slot, len(Player{}.Equipment))
+}
+
+func (m *MsgUnequip) Validate() error {
+	return checkSlot(m.Slot, len(Player{}.Equipment))
+}
+
+func (m *MsgUseHotbar) Validate() error {
+	return checkSlot(m.Slot, hotbarSize)
+}
+
+func (m *MsgHotbarSet) Validate() error {
+	return checkSlot(m.Slot, hotbarSize)
+}
+
+func (m *MsgMarketList) Validate() error {
+	if m.Price <= 0 {
+		return fmt.Errorf("price must be positive, got %d", m.Price)
+	}
+	return nil
+}
+
+func (m *MsgTradeGold) Validate() error {
+	if m.Amount < 0 {
+		return fmt.Errorf("amount must not be negative, got %d", m.Amount)
+	}
+	return nil
+}
+
+func (m *MsgCast) Validate() error {
+	if m.SkillID == "" {
+		return errors.New("skill_id is required")
+	}
+	return nil
+}
+
+func (m *MsgQuestAbandon) Validate() error {
+	if m.QuestID == "" {
+		return errors.New("quest_id is required")
+	}
+	return nil
+}
+
+func (m *MsgChannelSwitch) Validate() error {
+	if m.Channel < 1 {
+		return fmt.Errorf("channel must be at least 1, got %d", m.Channel)
+	}
+	return nil
+}
+
+func (m *MsgResume) Validate() error {
+	if m.Token == "" {
+		return errors.New("token is required")
+	}
+	return nil
+}
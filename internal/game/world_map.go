@@ -25,16 +25,38 @@ type WorldMap struct {
 
 	Width  float64
 	Height float64
+	PvP    bool
+
+	// Instanced maps are templates; players are sent to their own copy
+	Instanced bool
+
+	// Maps with a ChannelCap split into channels of roughly that many
+	// players. Extra channels point at their Base map.
+	ChannelCap int
+	Channel    int
+	Base       *WorldMap
+	emptySince time.Time
+
+	Walls     []Wall
+	Collision *CollisionGrid
+
+	Damage       *DamageConfig
+	cfg          *Config
+	damageEvents []DamageEvent
 
 	lastItemID int
 	lastMonID  int
 	lastProjID int
 
+	// now is the game clock as of the last update. Projectiles, drops and
+	// effects created between updates are stamped with it.
+	now time.Time
+
 	lock sync.RWMutex
 }
 
 func NewWorldMap(id string) *WorldMap {
-	return &WorldMap{
+	m := &WorldMap{
 		ID:          id,
 		Items:       make(map[int]*Item),
 		Monsters:    make(map[int]*Monster),
@@ -43,22 +65,43 @@ func NewWorldMap(id string) *WorldMap {
 		Portals:     make([]*Portal, 0),
 		Width:       800,
 		Height:      600,
+		Damage:      DefaultDamageConfig(),
+		Channel:     1,
 	}
+	m.Collision = NewCollisionGrid(m.Width, m.Height, nil)
+	cfg := DefaultConfig()
+	m.cfg = &cfg
+	return m
 }
 
-func (m *WorldMap) UpdateProjectiles() {
+// UpdateProjectiles moves projectiles forward by dt seconds, to game time now
+func (m *WorldMap) UpdateProjectiles(now time.Time, dt float64) {
 	m.lock.Lock()
 	defer m.lock.Unlock()
 
-	const speed = 10.0
+	m.now = now
 	idsToRemove := []int{}
 
 	for id, p := range m.Projectiles {
-		p.X += p.VX * speed
-		p.Y += p.VY * speed
+		def := p.def()
+		if def.Homing > 0 {
+			m.steer(p, def.Homing*dt)
+		}
+
+		step := def.Speed * dt
+		fromX, fromY := p.X, p.Y
+		p.X += p.VX * step
+		p.Y += p.VY * step
+		p.Traveled += step
 
 		if p.X < -50 || p.X > m.Width+50 || p.Y < -50 || p.Y > m.Height+50 {
 			idsToRemove = append(idsToRemove, id)
+		} else if m.Collision.SegmentBlocked(fromX, fromY, p.X, p.Y) {
+			idsToRemove = append(idsToRemove, id)
+		} else if def.Range > 0 && p.Traveled >= def.Range {
+			idsToRemove = append(idsToRemove, id)
+		} else if def.Lifetime > 0 && now.Sub(p.SpawnedAt) >= def.Lifetime {
+			idsToRemove = append(idsToRemove, id)
 		}
 	}
 
@@ -67,15 +110,15 @@ func (m *WorldMap) UpdateProjectiles() {
 	}
 }
 
-func (m *WorldMap) UpdateItems(players []*Player) {
+func (m *WorldMap) UpdateItems(players []*Player, now time.Time) {
 	m.lock.Lock()
 	defer m.lock.Unlock()
 
+	m.now = now
 	itemsToRemove := []int{}
-	now := time.Now()
 
 	for id, item := range m.Items {
-		if now.Sub(item.CreatedAt) > 2*time.Minute {
+		if now.Sub(item.CreatedAt) > m.cfg.ItemExpiry {
 			itemsToRemove = append(itemsToRemove, id)
 		}
 	}
@@ -90,13 +133,16 @@ func (m *WorldMap) UpdateItems(players []*Player) {
 	}
 }
 
-func (m *WorldMap) UpdatePlayerShooting(players []*Player) {
+func (m *WorldMap) UpdatePlayerShooting(players []*Player, now time.Time) {
 	m.lock.Lock()
 	defer m.lock.Unlock()
 
-	now := time.Now()
+	m.now = now
 	for _, p := range players {
-		if now.Sub(p.LastShoot) > time.Millisecond*500 {
+		if !p.AutoAttack {
+			continue
+		}
+		if now.Sub(p.LastShoot) > m.cfg.ShootCooldown {
 			var target *Monster
 			minDist := math.MaxFloat64
 
@@ -137,33 +183,42 @@ func (m *WorldMap) UpdatePlayerShooting(players []*Player) {
 					projectilesToFire = append(projectilesToFire, pType)
 				}
 			}
+			projectilesToFire = append(projectilesToFire, p.buffElements()...)
 
-			count := len(projectilesToFire)
-			step := 0.2
-			startAngle := -float64(count-1) * step / 2.0
-
-			for i, pType := range projectilesToFire {
-				angle := startAngle + float64(i)*step
-
-				m.lastProjID++
-				nvx := vx*math.Cos(angle) - vy*math.Sin(angle)
-				nvy := vx*math.Sin(angle) + vy*math.Cos(angle)
-
-				proj := &Projectile{
-					ID:      m.lastProjID,
-					OwnerID: p.ID,
-					X:       p.X,
-					Y:       p.Y,
-					VX:      nvx,
-					VY:      nvy,
-					Type:    pType,
-				}
-				m.Projectiles[proj.ID] = proj
-			}
+			m.fireSpread(p, vx, vy, projectilesToFire, 0.2, 1)
 		}
 	}
 }
 
+// fireSpread fires one projectile per entry in types, fanned out step radians
+// apart around (vx, vy). Caller must hold m.lock.
+func (m *WorldMap) fireSpread(p *Player, vx, vy float64, types []ProjectileType, step float64, power float64) {
+	count := len(types)
+	startAngle := -float64(count-1) * step / 2.0
+
+	for i, pType := range types {
+		angle := startAngle + float64(i)*step
+
+		m.lastProjID++
+		nvx := vx*math.Cos(angle) - vy*math.Sin(angle)
+		nvy := vx*math.Sin(angle) + vy*math.Cos(angle)
+
+		proj := &Projectile{
+			ID:        m.lastProjID,
+			OwnerID:   p.ID,
+			X:         p.X,
+			Y:         p.Y,
+			VX:        nvx,
+			VY:        nvy,
+			Type:      pType,
+			Power:     power,
+			SpawnedAt: m.clock(),
+		}
+		proj.PierceLeft = proj.def().Pierce
+		m.Projectiles[proj.ID] = proj
+	}
+}
+
 func (m *WorldMap) SpawnMonster() {
 	m.lock.Lock()
 	defer m.lock.Unlock()
@@ -172,23 +227,36 @@ func (m *WorldMap) SpawnMonster() {
 		return
 	}
 
+	x, y, ok := m.randomOpenPoint()
+	if !ok {
+		return
+	}
+
 	m.lastMonID++
 	mon := &Monster{
-		ID:    m.lastMonID,
-		X:     50 + rand.Float64()*(m.Width-100),
-		Y:     50 + rand.Float64()*(m.Height-100),
-		Type:  MonsterType(rand.Intn(3)),
-		HP:    50,
-		MaxHP: 50,
+		ID:     m.lastMonID,
+		X:      x,
+		Y:      y,
+		Type:   MonsterType(rand.Intn(3)),
+		HP:     50,
+		MaxHP:  50,
+		Attack: 5,
 	}
 	m.Monsters[mon.ID] = mon
 }
 
-func (m *WorldMap) UpdateMonsters(players []*Player) {
+// UpdateMonsters moves monsters towards their targets by dt seconds
+func (m *WorldMap) UpdateMonsters(players []*Player, dt float64) {
 	m.lock.Lock()
 	defer m.lock.Unlock()
 
-	const monsterSpeed = 2.0
+	const (
+		monsterSpeed = 60.0 // per second
+		separation   = 3.0  // push strength per second
+	)
+
+	now := m.clock()
+	budget := maxPathsPerTick
 
 	for _, mon := range m.Monsters {
 		var target *Player
@@ -207,11 +275,12 @@ func (m *WorldMap) UpdateMonsters(players []*Player) {
 		vx, vy := 0.0, 0.0
 
 		if target != nil {
-			dx := target.X - mon.X
-			dy := target.Y - mon.Y
+			hx, hy := m.steerMonster(mon, target.X, target.Y, now, &budget)
+			dx := hx - mon.X
+			dy := hy - mon.Y
 			dist := math.Sqrt(dx*dx + dy*dy)
 
-			if dist > monsterSpeed {
+			if dist > monsterSpeed*dt {
 				vx = (dx / dist) * monsterSpeed
 				vy = (dy / dist) * monsterSpeed
 			}
@@ -229,16 +298,80 @@ func (m *WorldMap) UpdateMonsters(players []*Player) {
 			if distSq < collisionDistance*collisionDistance && distSq > 0 {
 				dist := math.Sqrt(distSq)
 				push := (collisionDistance - dist) / dist
-				vx += dx * push * 0.1
-				vy += dy * push * 0.1
+				vx += dx * push * separation
+				vy += dy * push * separation
 			}
 		}
 
-		mon.X += vx
-		mon.Y += vy
+		step := mon.Effects.SpeedFactor() * dt
+		mon.X, mon.Y = m.Collision.Slide(mon.X, mon.Y, mon.X+vx*step, mon.Y+vy*step)
 	}
 }
 
+// randomOpenPoint picks a spawn point away from the edges and off any wall.
+// After a few misses it falls back to the first open tile; ok is false if
+// the map has none. Caller must hold m.lock.
+func (m *WorldMap) randomOpenPoint() (x, y float64, ok bool) {
+	const attempts = 50
+	for i := 0; i < attempts; i++ {
+		x = 50 + rand.Float64()*(m.Width-100)
+		y = 50 + rand.Float64()*(m.Height-100)
+		if !m.Collision.Blocked(x, y) {
+			return x, y, true
+		}
+	}
+
+	c := m.Collision
+	for row := 0; row < c.Rows; row++ {
+		for col := 0; col < c.Cols; col++ {
+			if !c.Solid(col, row) {
+				return (float64(col) + 0.5) * tileSize, (float64(row) + 0.5) * tileSize, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// UpdateMonsterAttacks lets monsters hit players they are touching.
+// Players brought to 0 HP are respawned by the game after the tick.
+func (m *WorldMap) UpdateMonsterAttacks(players []*Player, now time.Time) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	const attackRange = 25.0
+	const attackCooldown = time.Second
+
+	m.now = now
+	for _, mon := range m.Monsters {
+		if now.Sub(mon.LastAttack) < attackCooldown {
+			continue
+		}
+		for _, p := range players {
+			if p.HP <= 0 {
+				continue
+			}
+			dx := p.X - mon.X
+			dy := p.Y - mon.Y
+			if dx*dx+dy*dy >= attackRange*attackRange {
+				continue
+			}
+
+			mon.LastAttack = now
+			roll := m.Damage.Roll(mon.Attack, mon.Type.Element(), ElementNeutral, p.Defense)
+			p.HP -= roll.Amount
+			if p.HP < 0 {
+				p.HP = 0
+			}
+			p.lastAttackerID = 0
+			m.addDamageEvent(DamageTargetPlayer, p.ID, 0, p.X, p.Y, roll)
+			p.SendStats()
+			break
+		}
+	}
+}
+
+const playerHitRadius = 15.0
+
 func (m *WorldMap) CheckCollisions(players []*Player) {
 	m.lock.Lock()
 	defer m.lock.Unlock()
@@ -250,61 +383,49 @@ func (m *WorldMap) CheckCollisions(players []*Player) {
 	}
 
 	projToRemove := make(map[int]bool)
-	monstersToKill := []int{}
 
 	for pid, proj := range m.Projectiles {
-		for mid, mon := range m.Monsters {
+		def := proj.def()
+		owner := playerMap[proj.OwnerID]
+
+		for _, mon := range m.Monsters {
+			if mon.HP <= 0 || proj.hitMonsters[mon.ID] {
+				continue
+			}
 			dx := proj.X - mon.X
 			dy := proj.Y - mon.Y
-
-			hitRadius := 20.0
-			if proj.Type == ProjectileTypeGrass {
-				hitRadius = 40.0
+			if dx*dx+dy*dy >= def.HitRadius*def.HitRadius {
+				continue
 			}
 
-			if dx*dx+dy*dy < hitRadius*hitRadius {
+			m.projectileHitMonster(proj, owner, mon, players)
+			if !proj.pierce() {
 				projToRemove[pid] = true
+				break
+			}
+		}
 
-				damage := 10
-				if owner, ok := playerMap[proj.OwnerID]; ok {
-					damage = owner.Attack
-
-					isEffective := false
-					isNotEffective := false
-
-					if proj.Type == ProjectileTypeFire && mon.Type == MonsterTypeGrass {
-						isEffective = true
-					} else if proj.Type == ProjectileTypeWater && mon.Type == MonsterTypeFire {
-						isEffective = true
-					} else if proj.Type == ProjectileTypeGrass && mon.Type == MonsterTypeWater {
-						isEffective = true
-					}
-
-					if proj.Type == ProjectileTypeFire && mon.Type == MonsterTypeWater {
-						isNotEffective = true
-					} else if proj.Type == ProjectileTypeWater && mon.Type == MonsterTypeGrass {
-						isNotEffective = true
-					} else if proj.Type == ProjectileTypeGrass && mon.Type == MonsterTypeFire {
-						isNotEffective = true
-					}
-
-					if isEffective {
-						damage *= 2
-					} else if isNotEffective {
-						damage /= 2
-					}
-
-					if isEffective {
-						damage *= 2
-					}
-				}
-				mon.HP -= damage
+		if projToRemove[pid] {
+			continue
+		}
 
-				if mon.HP <= 0 {
-					monstersToKill = append(monstersToKill, mid)
-					m.spawnItemAt(mon.X, mon.Y, players)
-				}
+		for _, target := range players {
+			if !m.canDamage(owner, target) || proj.hitPlayers[target.ID] {
+				continue
+			}
+			dx := proj.X - target.X
+			dy := proj.Y - target.Y
+			if dx*dx+dy*dy >= playerHitRadius*playerHitRadius {
+				continue
+			}
 
+			if proj.hitPlayers == nil {
+				proj.hitPlayers = make(map[int]bool)
+			}
+			proj.hitPlayers[target.ID] = true
+			m.hitPlayer(owner, target, proj.attack(owner), proj.Type)
+			if !proj.pierce() {
+				projToRemove[pid] = true
 				break
 			}
 		}
@@ -313,9 +434,6 @@ func (m *WorldMap) CheckCollisions(players []*Player) {
 	for pid := range projToRemove {
 		delete(m.Projectiles, pid)
 	}
-	for _, mid := range monstersToKill {
-		delete(m.Monsters, mid)
-	}
 
 	const collectRadius = 15.0
 	for _, p := range players {
@@ -329,6 +447,16 @@ func (m *WorldMap) CheckCollisions(players []*Player) {
 	}
 }
 
+// killMonster removes a dead monster, drops its loot and credits killer,
+// which may be nil. Caller must hold m.lock.
+func (m *WorldMap) killMonster(mon *Monster, killer *Player, players []*Player) {
+	delete(m.Monsters, mon.ID)
+	m.spawnItemAt(mon.X, mon.Y, players)
+	if killer != nil {
+		killer.onMonsterKilled(mon)
+	}
+}
+
 func (m *WorldMap) spawnItemAt(x, y float64, players []*Player) {
 	m.lastItemID++
 
@@ -337,31 +465,40 @@ func (m *WorldMap) spawnItemAt(x, y float64, players []*Player) {
 	var name string
 	var atk, def int
 	var projType ProjectileType
+	var consumableID string
+	var count int
 
 	if randVal < 0.5 {
 		iType = ItemTypeGold
 		name = "Gold"
-	} else if randVal < 0.75 {
+	} else if randVal < 0.7 {
 		iType = ItemTypeWeapon
 		name = "Sword"
 		atk = 5 + rand.Intn(10)
 		projType = ProjectileType(1 + rand.Intn(3))
-	} else {
+	} else if randVal < 0.9 {
 		iType = ItemTypeArmor
 		name = "Shield"
 		def = 2 + rand.Intn(5)
+	} else {
+		iType = ItemTypeConsumable
+		consumableID = "potion_small"
+		name = consumableDefs[consumableID].Name
+		count = 1
 	}
 
 	item := &Item{
 		ID:             m.lastItemID,
 		X:              x,
 		Y:              y,
-		CreatedAt:      time.Now(),
+		CreatedAt:      m.clock(),
 		Type:           iType,
 		Name:           name,
 		Attack:         atk,
 		Defense:        def,
 		ProjectileType: projType,
+		ConsumableID:   consumableID,
+		Count:          count,
 	}
 	m.Items[item.ID] = item
 
@@ -375,28 +512,55 @@ func (m *WorldMap) spawnItemAt(x, y float64, players []*Player) {
 	m.broadcastJSON(msg, players)
 }
 
-func (m *WorldMap) collectItem(p *Player, item *Item, players []*Player) {
-	if item.Type != ItemTypeGold && len(p.Inventory) >= 20 {
-		return
+// spawnGoldAt drops a gold pile worth amount. Caller must hold m.lock.
+func (m *WorldMap) spawnGoldAt(x, y float64, amount int, players []*Player) {
+	m.lastItemID++
+	item := &Item{
+		ID:        m.lastItemID,
+		Type:      ItemTypeGold,
+		Name:      "Gold",
+		X:         x,
+		Y:         y,
+		CreatedAt: m.clock(),
+		Count:     amount,
 	}
+	m.Items[item.ID] = item
 
-	delete(m.Items, item.ID)
+	m.broadcastJSON(MsgItemSpawn{
+		Type:     "ITEM_SPAWN",
+		ID:       item.ID,
+		ItemType: int(item.Type),
+		X:        item.X,
+		Y:        item.Y,
+	}, players)
+}
 
+func (m *WorldMap) collectItem(p *Player, item *Item, players []*Player) {
 	if item.Type == ItemTypeGold {
-		p.Gold += 100
+		amount := m.cfg.GoldValue
+		if item.Count > 0 {
+			amount = item.Count
+		}
+		p.Gold += amount
 		p.SendJSON(MsgGoldUpdate{
 			Type:   "GOLD_UPDATE",
 			Amount: p.Gold,
 		})
 	} else {
-		p.Inventory = append(p.Inventory, item)
+		if !p.addItem(item) {
+			return
+		}
 		p.SendInventory()
 	}
 
+	delete(m.Items, item.ID)
+
 	m.broadcastJSON(MsgItemRemove{
 		Type: "ITEM_REMOVE",
 		ID:   item.ID,
 	}, players)
+
+	p.onItemCollected(item)
 }
 
 func (m *WorldMap) broadcastJSON(v interface{}, players []*Player) {
@@ -405,8 +569,32 @@ func (m *WorldMap) broadcastJSON(v interface{}, players []*Player) {
 	}
 }
 
+// clock returns the game time to stamp new projectiles, drops and effects
+// with. Maps that have not been updated yet fall back to the wall clock.
+// Caller must hold m.lock.
+func (m *WorldMap) clock() time.Time {
+	if m.now.IsZero() {
+		return time.Now()
+	}
+	return m.now
+}
+
+// setClock moves the map's clock to now without updating anything, so maps
+// skipped by a tick don't stamp new projectiles with a stale time
+func (m *WorldMap) setClock(now time.Time) {
+	m.lock.Lock()
+	m.now = now
+	m.lock.Unlock()
+}
+
+// AddProjectile puts proj in flight. Projectiles without a spawn time start
+// now with their type's pierce charges.
 func (m *WorldMap) AddProjectile(proj *Projectile) {
 	m.lock.Lock()
 	defer m.lock.Unlock()
+	if proj.SpawnedAt.IsZero() {
+		proj.SpawnedAt = m.clock()
+		proj.PierceLeft = proj.def().Pierce
+	}
 	m.Projectiles[proj.ID] = proj
 }
@@ -1,65 +1,176 @@
 package network
 
+//go:generate go run ../../cmd/msgdoc -o ../../docs/messages.md
+
 import (
-	"encoding/json"
+	"log"
 	"mmorpg/internal/game"
-	"strings"
+	"time"
 )
 
-// HandleCommand processes a single line of text or JSON from a player
-func HandleCommand(player *game.Player, text string) {
-	text = strings.TrimSpace(text)
-	if len(text) == 0 {
-		return
-	}
+// Handlers declares every client message. Adding a message means adding
+// its struct to game/protocol.go and a Register call here, then running
+// go generate to update docs/messages.md.
+var Handlers = newHandlers()
 
-	// Try parsing as JSON first
-	var msg map[string]interface{}
-	if err := json.Unmarshal([]byte(text), &msg); err == nil {
-		msgType, ok := msg["type"].(string)
-		if !ok {
-			return
-		}
+func newHandlers() *Registry {
+	r := NewRegistry()
+	r.Use(RateLimited)
 
-		switch msgType {
-		case "MOVE":
-			var move game.MsgMove
-			if err := json.Unmarshal([]byte(text), &move); err == nil {
-				player.Move(move.X, move.Y)
-			}
-		case "EQUIP":
-			var equip game.MsgEquip
-			if err := json.Unmarshal([]byte(text), &equip); err == nil {
-				player.Equip(equip.ItemID, equip.Slot)
-			}
-		case "UNEQUIP":
-			var unequip game.MsgUnequip
-			if err := json.Unmarshal([]byte(text), &unequip); err == nil {
-				player.Unequip(unequip.Slot)
-			}
-		case "SELL":
-			var sell game.MsgSell
-			if err := json.Unmarshal([]byte(text), &sell); err == nil {
-				player.Sell(sell.ItemID)
-			}
-		case "MARKET_LIST":
-			var list game.MsgMarketList
-			if err := json.Unmarshal([]byte(text), &list); err == nil {
-				if player.Game() != nil {
-					player.Game().ListMarketItem(player, list.ItemID, list.Price)
-				}
-			}
-		case "MARKET_BUY":
-			var buy game.MsgMarketBuy
-			if err := json.Unmarshal([]byte(text), &buy); err == nil {
-				if player.Game() != nil {
-					player.Game().BuyMarketItem(player, buy.MarketID)
-				}
+	Register(r, "MOVE", "Move to a position. The server slides it along walls.",
+		func(c *Context, m *game.MsgMove) {
+			if g := c.Player.Game(); g != nil {
+				g.MovePlayer(c.Player, m.X, m.Y)
+			} else {
+				c.Player.Move(m.X, m.Y)
 			}
+		})
+	Register(r, "RESUME", "Take back a disconnected player with the token from its WELCOME.",
+		func(c *Context, m *game.MsgResume) {
+			c.Player, _ = c.Player.Game().ResumePlayer(c.Player, m.Token)
+		}, InGame, Immediate)
+
+	// Items
+	Register(r, "EQUIP", "Equip an inventory item into an equipment slot (0-4).",
+		func(c *Context, m *game.MsgEquip) { c.Player.Equip(m.ItemID, m.Slot) })
+	Register(r, "UNEQUIP", "Move the item in an equipment slot back to the inventory.",
+		func(c *Context, m *game.MsgUnequip) { c.Player.Unequip(m.Slot) })
+	Register(r, "SELL", "Sell an inventory item to a nearby shop.",
+		func(c *Context, m *game.MsgSell) { c.Player.Sell(m.ItemID) })
+	Register(r, "USE_ITEM", "Use a consumable from the inventory.",
+		func(c *Context, m *game.MsgUseItem) { c.Player.UseItem(m.ItemID) })
+	Register(r, "USE_HOTBAR", "Use the item bound to a hotbar slot.",
+		func(c *Context, m *game.MsgUseHotbar) { c.Player.UseHotbar(m.Slot) })
+	Register(r, "HOTBAR_SET", "Bind an inventory item to a hotbar slot.",
+		func(c *Context, m *game.MsgHotbarSet) { c.Player.SetHotbar(m.Slot, m.ItemID) })
+
+	// Market
+	Register(r, "MARKET_LIST", "List an inventory item on the player market.",
+		func(c *Context, m *game.MsgMarketList) {
+			c.Player.Game().ListMarketItem(c.Player, m.ItemID, m.Price)
+		}, InGame, Logged)
+	Register(r, "MARKET_BUY", "Buy a listing from the player market.",
+		func(c *Context, m *game.MsgMarketBuy) {
+			c.Player.Game().BuyMarketItem(c.Player, m.MarketID)
+		}, InGame, Logged)
+
+	// Trade
+	Register(r, "TRADE_REQUEST", "Ask another player to trade.",
+		func(c *Context, m *game.MsgTradeRequest) {
+			c.Player.Game().RequestTrade(c.Player, m.TargetID)
+		}, InGame)
+	Register(r, "TRADE_ACCEPT", "Accept a trade request.",
+		func(c *Context, m *game.MsgTradeAccept) {
+			c.Player.Game().AcceptTrade(c.Player, m.TradeID)
+		}, InGame)
+	Register(r, "TRADE_ADD_ITEM", "Offer an inventory item in the open trade.",
+		func(c *Context, m *game.MsgTradeItem) {
+			c.Player.Game().TradeAddItem(c.Player, m.ItemID)
+		}, InGame)
+	Register(r, "TRADE_REMOVE_ITEM", "Take an offered item back.",
+		func(c *Context, m *game.MsgTradeItem) {
+			c.Player.Game().TradeRemoveItem(c.Player, m.ItemID)
+		}, InGame)
+	Register(r, "TRADE_SET_GOLD", "Set the gold offered in the open trade.",
+		func(c *Context, m *game.MsgTradeGold) {
+			c.Player.Game().TradeSetGold(c.Player, m.Amount)
+		}, InGame)
+	Register(r, "TRADE_CONFIRM", "Confirm the trade; it completes when both sides confirm.",
+		func(c *Context, _ *struct{}) { c.Player.Game().ConfirmTrade(c.Player) }, InGame, Logged)
+	Register(r, "TRADE_CANCEL", "Cancel the open trade.",
+		func(c *Context, _ *struct{}) { c.Player.Game().CancelTrade(c.Player) }, InGame)
+
+	// NPCs and quests
+	Register(r, "INTERACT", "Talk to a nearby NPC.",
+		func(c *Context, m *game.MsgInteract) {
+			c.Player.Game().Interact(c.Player, m.NPCID)
+		}, InGame)
+	Register(r, "DIALOG_CHOICE", "Pick an option in the open NPC dialog.",
+		func(c *Context, m *game.MsgDialogChoice) {
+			c.Player.Game().ChooseDialogOption(c.Player, m.NPCID, m.Option)
+		}, InGame)
+	Register(r, "BUY", "Buy an entry from an NPC shop.",
+		func(c *Context, m *game.MsgBuy) {
+			c.Player.Game().BuyShopItem(c.Player, m.NPCID, m.EntryID)
+		}, InGame)
+	Register(r, "QUEST_ABANDON", "Drop an active quest.",
+		func(c *Context, m *game.MsgQuestAbandon) {
+			c.Player.Game().AbandonQuest(c.Player, m.QuestID)
+		}, InGame)
+
+	// Combat
+	Register(r, "CAST", "Cast a skill at a target or in a direction.",
+		func(c *Context, m *game.MsgCast) {
+			c.Player.Game().Cast(c.Player, m.SkillID, m.TargetID, m.DirX, m.DirY)
+		}, InGame)
+	Register(r, "AUTO_ATTACK", "Turn automatic shooting at the nearest monster on or off.",
+		func(c *Context, m *game.MsgAutoAttack) { c.Player.SetAutoAttack(m.Enabled) })
+	Register(r, "DUEL_REQUEST", "Challenge another player to a duel.",
+		func(c *Context, m *game.MsgDuelRequest) {
+			c.Player.Game().RequestDuel(c.Player, m.TargetID)
+		}, InGame)
+	Register(r, "DUEL_ACCEPT", "Accept a duel challenge.",
+		func(c *Context, m *game.MsgDuelAccept) {
+			c.Player.Game().AcceptDuel(c.Player, m.DuelID)
+		}, InGame)
+	Register(r, "DUEL_CANCEL", "Withdraw a challenge or forfeit the duel.",
+		func(c *Context, _ *struct{}) { c.Player.Game().CancelDuel(c.Player) }, InGame)
+
+	// Party and channels
+	Register(r, "PARTY_INVITE", "Invite another player to your party.",
+		func(c *Context, m *game.MsgPartyRequest) {
+			c.Player.Game().InviteToParty(c.Player, m.TargetID)
+		}, InGame)
+	Register(r, "PARTY_ACCEPT", "Join the party you were invited to.",
+		func(c *Context, m *game.MsgPartyAccept) {
+			c.Player.Game().AcceptParty(c.Player, m.PartyID)
+		}, InGame)
+	Register(r, "PARTY_LEAVE", "Leave your party.",
+		func(c *Context, _ *struct{}) { c.Player.Game().LeaveParty(c.Player) }, InGame)
+	Register(r, "CHANNEL_SWITCH", "Move to another channel of the current map.",
+		func(c *Context, m *game.MsgChannelSwitch) {
+			c.Player.Game().SwitchChannel(c.Player, m.Channel)
+		}, InGame)
+
+	return r
+}
+
+// InGame drops messages from players not attached to a game
+func InGame(next HandlerFunc) HandlerFunc {
+	return func(c *Context) error {
+		if c.Player.Game() == nil {
+			return nil
 		}
-		return
+		return next(c)
 	}
+}
 
-	// Fallback for debugging/legacy (optional)
-	// fmt.Printf("Unknown command from %d: %s\n", player.ID, text)
+// Immediate handles the message as soon as it arrives rather than on the
+// next tick, for session-level messages that must take effect before the
+// next frame is read
+func Immediate(next HandlerFunc) HandlerFunc {
+	return func(c *Context) error {
+		c.Immediate = true
+		return next(c)
+	}
+}
+
+// Logged logs the message, for actions worth auditing such as trades
+func Logged(next HandlerFunc) HandlerFunc {
+	return func(c *Context) error {
+		err := next(c)
+		log.Printf("Player %d %s: %s", c.Player.ID, c.Type, c.Frame)
+		return err
+	}
+}
+
+// RateLimited drops messages over the session's per-type limits. Sessions
+// that keep sending too much are kicked.
+func RateLimited(next HandlerFunc) HandlerFunc {
+	return func(c *Context) error {
+		if c.Session != nil && !c.Session.allow(c.Type, time.Now()) {
+			return ErrRateLimited
+		}
+		return next(c)
+	}
 }
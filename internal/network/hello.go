@@ -0,0 +1,140 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"mmorpg/internal/game"
+	"strings"
+	"time"
+)
+
+// helloWait is how long a new connection has to send HELLO. Clients from
+// before HELLO may wait for the server to speak first; those still silent
+// after helloWait are served as version 1.
+const helloWait = 2 * time.Second
+
+// Encodings the server can send, in order of preference
+var serverCodecs = []string{"json"}
+
+// Optional features a client may ask for in HELLO. Others, such as
+// "delta_snapshots", are not offered yet and are left out of HELLO_OK.
+const (
+	CapResume      = "resume"      // RESUME after a dropped connection
+	CapCompression = "compression" // permessage-deflate, if the transport negotiated it
+)
+
+// compressor is implemented by codecs that can tell whether they compress
+type compressor interface {
+	Compressed() bool
+}
+
+// negotiate picks the version, codec and capabilities for hello, or
+// returns why the client can't be served
+func negotiate(hello game.MsgHello, compressed bool) (game.MsgHelloOK, string) {
+	ok := game.MsgHelloOK{Type: "HELLO_OK", Version: hello.Version, Capabilities: []string{}}
+
+	if hello.Version < game.MinProtocolVersion || hello.Version > game.ProtocolVersion {
+		return ok, fmt.Sprintf("protocol version %d is not supported, the server speaks %d to %d",
+			hello.Version, game.MinProtocolVersion, game.ProtocolVersion)
+	}
+
+	codecs := hello.Codecs
+	if len(codecs) == 0 {
+		codecs = serverCodecs[:1]
+	}
+	for _, c := range codecs {
+		if ok.Codec == "" && contains(serverCodecs, c) {
+			ok.Codec = c
+		}
+	}
+	if ok.Codec == "" {
+		return ok, fmt.Sprintf("none of the codecs %s is supported, the server speaks %s",
+			strings.Join(hello.Codecs, ", "), strings.Join(serverCodecs, ", "))
+	}
+
+	for _, c := range hello.Capabilities {
+		if c == CapResume || (c == CapCompression && compressed) {
+			ok.Capabilities = append(ok.Capabilities, c)
+		}
+	}
+	return ok, ""
+}
+
+// readResult is the outcome of one ReadFrame
+type readResult struct {
+	frame []byte
+	err   error
+}
+
+// handshake waits for the client's HELLO and answers it. It returns the
+// negotiated protocol version, or 0 once the session has been closed.
+// Clients from before HELLO start with a game message, or say nothing
+// until helloWait has passed; they speak version 1. Their first read is
+// returned as pending, to be taken once the player has joined. It runs
+// before the writer goroutine, so it writes to the codec directly.
+func (s *Session) handshake() (version int, pending <-chan readResult) {
+	// The first read runs on its own goroutine: a read that times out
+	// can't be resumed, and a silent client must still be readable later
+	first := make(chan readResult, 1)
+	s.extendReadDeadline()
+	go func() {
+		frame, err := s.codec.ReadFrame()
+		first <- readResult{frame, err}
+	}()
+
+	var r readResult
+	timer := time.NewTimer(helloWait)
+	defer timer.Stop()
+	select {
+	case r = <-first:
+	case <-timer.C:
+		return game.MinProtocolVersion, first
+	}
+	if r.err != nil {
+		s.closeOnReadError(r.err)
+		return 0, nil
+	}
+
+	var hello game.MsgHello
+	if json.Unmarshal(r.frame, &hello) != nil || hello.Type != "HELLO" {
+		first <- r
+		return game.MinProtocolVersion, first
+	}
+
+	cc, _ := s.codec.(compressor)
+	ok, reason := negotiate(hello, cc != nil && cc.Compressed())
+	if reason != "" {
+		s.reject(reason)
+		return 0, nil
+	}
+	s.writeNow(ok)
+	return ok.Version, nil
+}
+
+func (s *Session) reject(reason string) {
+	s.writeNow(game.MsgHelloRejected{
+		Type:       "HELLO_REJECTED",
+		Reason:     reason,
+		MinVersion: game.MinProtocolVersion,
+		MaxVersion: game.ProtocolVersion,
+	})
+	s.CloseWithReason(CloseIncompatible)
+}
+
+func (s *Session) writeNow(v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	s.setWriteDeadline()
+	s.codec.WriteFrame(data)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
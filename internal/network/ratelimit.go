@@ -0,0 +1,170 @@
+package network
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// violationWindow is how far back rate limit violations are counted
+const violationWindow = 10 * time.Second
+
+// RateLimit is a token bucket: Rate messages per second on average, with
+// bursts of up to Burst messages. Messages over the limit are dropped and
+// count towards a kick, unless Silent is set.
+type RateLimit struct {
+	Rate   float64 `json:"rate"`
+	Burst  int     `json:"burst"`
+	Silent bool    `json:"silent"`
+}
+
+// DefaultRateLimitKey holds the limit for message types without their own
+const DefaultRateLimitKey = "default"
+
+// DefaultRateLimits allows normal play with room to spare. MOVE is sent
+// every animation frame, which on fast screens is more than any limit
+// worth having, so extra MOVEs are dropped without counting against the
+// player. Each MOVE carries the full position, so nothing is lost.
+func DefaultRateLimits() map[string]RateLimit {
+	return map[string]RateLimit{
+		DefaultRateLimitKey: {Rate: 10, Burst: 20},
+		"MOVE":              {Rate: 200, Burst: 200, Silent: true},
+		"MARKET_LIST":       {Rate: 2, Burst: 5},
+		"MARKET_BUY":        {Rate: 2, Burst: 5},
+		"TRADE_REQUEST":     {Rate: 1, Burst: 3},
+		"DUEL_REQUEST":      {Rate: 1, Burst: 3},
+		"PARTY_INVITE":      {Rate: 1, Burst: 3},
+		"RESUME":            {Rate: 1, Burst: 3},
+	}
+}
+
+type tokenBucket struct {
+	limit  RateLimit
+	tokens float64
+	last   time.Time
+}
+
+func (b *tokenBucket) allow(now time.Time) bool {
+	b.tokens += now.Sub(b.last).Seconds() * b.limit.Rate
+	if max := float64(b.limit.Burst); b.tokens > max {
+		b.tokens = max
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// limiter keeps one bucket per message type for a session. It is only used
+// by the session's read loop.
+type limiter struct {
+	limits     map[string]RateLimit
+	buckets    map[string]*tokenBucket
+	violations []time.Time
+}
+
+func newLimiter(limits map[string]RateLimit) *limiter {
+	return &limiter{limits: limits, buckets: make(map[string]*tokenBucket)}
+}
+
+// allow reports whether a message of type typ may be handled now, and if
+// not, whether dropping it counts as a violation. Types without a limit
+// share the default bucket; with no default they are free.
+func (l *limiter) allow(typ string, now time.Time) (ok, violation bool) {
+	limit, found := l.limits[typ]
+	if !found {
+		typ = DefaultRateLimitKey
+		if limit, found = l.limits[typ]; !found {
+			return true, false
+		}
+	}
+
+	b, found := l.buckets[typ]
+	if !found {
+		b = &tokenBucket{limit: limit, tokens: float64(limit.Burst), last: now}
+		l.buckets[typ] = b
+	}
+	if b.allow(now) {
+		return true, false
+	}
+	return false, !limit.Silent
+}
+
+// violate records a dropped message and returns how many were dropped
+// within violationWindow
+func (l *limiter) violate(now time.Time) int {
+	kept := l.violations[:0]
+	for _, t := range l.violations {
+		if now.Sub(t) < violationWindow {
+			kept = append(kept, t)
+		}
+	}
+	l.violations = append(kept, now)
+	return len(l.violations)
+}
+
+// banList escalates repeated rate limit kicks from one host to a ban
+type banList struct {
+	mu     sync.Mutex
+	kicks  map[string][]time.Time
+	banned map[string]time.Time // host -> ban expiry
+}
+
+func newBanList() *banList {
+	return &banList{kicks: make(map[string][]time.Time), banned: make(map[string]time.Time)}
+}
+
+// kick records a kick of host and bans it for d once it has been kicked
+// after times within d. It reports whether host is now banned.
+func (b *banList) kick(host string, now time.Time, after int, d time.Duration) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if after <= 0 || d <= 0 {
+		return false
+	}
+	for h, ts := range b.kicks {
+		if now.Sub(ts[len(ts)-1]) >= d {
+			delete(b.kicks, h)
+		}
+	}
+
+	kept := b.kicks[host][:0]
+	for _, t := range b.kicks[host] {
+		if now.Sub(t) < d {
+			kept = append(kept, t)
+		}
+	}
+	kept = append(kept, now)
+	if len(kept) < after {
+		b.kicks[host] = kept
+		return false
+	}
+
+	delete(b.kicks, host)
+	b.banned[host] = now.Add(d)
+	return true
+}
+
+func (b *banList) isBanned(host string, now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	until, ok := b.banned[host]
+	if ok && !now.Before(until) {
+		delete(b.banned, host)
+		return false
+	}
+	return ok
+}
+
+// hostOf strips the port from a remote address
+func hostOf(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
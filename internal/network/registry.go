@@ -0,0 +1,209 @@
+package network
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mmorpg/internal/game"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrUnknownMessage = errors.New("unknown message type")
+	ErrRateLimited    = errors.New("rate limited")
+)
+
+// Context is one incoming message on its way to a handler
+type Context struct {
+	Type  string
+	Frame []byte
+	// Player sends the message. Handlers may replace it, as RESUME does,
+	// and the session continues as the new player.
+	Player *game.Player
+	// Session is the sending connection, nil when dispatching without one
+	Session *Session
+	// Immediate runs the handler on the calling goroutine instead of
+	// queueing it for the next game tick. See the Immediate middleware.
+	Immediate bool
+}
+
+// HandlerFunc handles a message whose type is already known
+type HandlerFunc func(c *Context) error
+
+// Middleware wraps a handler to run code around it or stop the message
+type Middleware func(next HandlerFunc) HandlerFunc
+
+// Validator is implemented by messages that check their own fields after
+// decoding. A message failing validation is not handled.
+type Validator interface {
+	Validate() error
+}
+
+type route struct {
+	doc     string
+	msg     reflect.Type
+	handler HandlerFunc
+}
+
+// Registry maps message types to their decoder and handler
+type Registry struct {
+	routes     map[string]*route
+	middleware []Middleware
+}
+
+func NewRegistry() *Registry {
+	return &Registry{routes: make(map[string]*route)}
+}
+
+// Use adds middleware run for every message, known or not, before any
+// route middleware. Middleware added first runs first.
+func (r *Registry) Use(mw ...Middleware) {
+	r.middleware = append(r.middleware, mw...)
+}
+
+// Register declares message type typ: frames are decoded into M, validated
+// if M implements Validator, and passed to fn through mw. doc is a one-line
+// description for the message list. fn runs on the game loop at the start
+// of the next tick unless the message is Immediate or the player has no
+// game; changes it makes to c.Player are then not seen by the session.
+func Register[M any](r *Registry, typ, doc string, fn func(c *Context, msg *M), mw ...Middleware) {
+	if _, ok := r.routes[typ]; ok {
+		panic("network: message " + typ + " registered twice")
+	}
+
+	var h HandlerFunc = func(c *Context) error {
+		msg := new(M)
+		if err := json.Unmarshal(c.Frame, msg); err != nil {
+			return fmt.Errorf("decode %s: %w", typ, err)
+		}
+		if v, ok := any(msg).(Validator); ok {
+			if err := v.Validate(); err != nil {
+				return fmt.Errorf("invalid %s: %w", typ, err)
+			}
+		}
+		if g := c.Player.Game(); g != nil && !c.Immediate {
+			g.Enqueue(c.Player, func() { fn(c, msg) })
+		} else {
+			fn(c, msg)
+		}
+		return nil
+	}
+	for i := len(mw) - 1; i >= 0; i-- {
+		h = mw[i](h)
+	}
+
+	r.routes[typ] = &route{doc: doc, msg: reflect.TypeOf((*M)(nil)).Elem(), handler: h}
+}
+
+// Dispatch runs the handler for a frame. c.Frame and c.Type are filled in
+// from frame.
+func (r *Registry) Dispatch(c *Context, frame []byte) error {
+	c.Frame = frame
+	c.Type = frameType(frame)
+
+	h := r.route
+	for i := len(r.middleware) - 1; i >= 0; i-- {
+		h = r.middleware[i](h)
+	}
+	return h(c)
+}
+
+func (r *Registry) route(c *Context) error {
+	rt, ok := r.routes[c.Type]
+	if !ok {
+		return ErrUnknownMessage
+	}
+	return rt.handler(c)
+}
+
+// frameType returns the type of a message, empty if it isn't valid JSON
+func frameType(frame []byte) string {
+	var msg struct {
+		Type string `json:"type"`
+	}
+	json.Unmarshal(frame, &msg)
+	return msg.Type
+}
+
+// MessageField describes one JSON field of a message
+type MessageField struct {
+	Name string
+	Type string // JSON type: number, string, boolean, array or object
+}
+
+// MessageInfo describes a registered message for client authors
+type MessageInfo struct {
+	Type   string
+	Doc    string
+	Fields []MessageField
+}
+
+// Messages lists the registered messages sorted by type
+func (r *Registry) Messages() []MessageInfo {
+	var infos []MessageInfo
+	for typ, rt := range r.routes {
+		info := MessageInfo{Type: typ, Doc: rt.doc}
+		for i := 0; i < rt.msg.NumField(); i++ {
+			f := rt.msg.Field(i)
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if !f.IsExported() || name == "-" || name == "type" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			info.Fields = append(info.Fields, MessageField{Name: name, Type: jsonType(f.Type)})
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Type < infos[j].Type })
+	return infos
+}
+
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct, reflect.Pointer:
+		return "object"
+	default:
+		return "number"
+	}
+}
+
+// WriteMarkdown writes the message list as a Markdown document
+func (r *Registry) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("# Client messages\n\n")
+	b.WriteString("<!-- Generated by cmd/msgdoc from the handler registry; DO NOT EDIT. -->\n\n")
+	b.WriteString("Every message is a JSON object with a `type` field plus the fields below.\n")
+	b.WriteString("WebSocket clients send one message per text frame, TCP clients one per line\n")
+	b.WriteString("and must also answer each `{\"type\":\"PING\"}` line with `{\"type\":\"PONG\"}`.\n")
+	fmt.Fprintf(&b, "\nThe first message should be `HELLO` with the client's protocol `version` (%d to %d),\n",
+		game.MinProtocolVersion, game.ProtocolVersion)
+	b.WriteString("the `codecs` it reads and the optional `capabilities` it supports. The server\n")
+	b.WriteString("answers `HELLO_OK` with what it enabled, or `HELLO_REJECTED` with a reason and closes.\n")
+	fmt.Fprintf(&b, "Clients that start with any other message, or send nothing for %v, are served as version %d.\n",
+		helloWait, game.MinProtocolVersion)
+
+	for _, m := range r.Messages() {
+		fmt.Fprintf(&b, "\n## %s\n\n%s\n", m.Type, m.Doc)
+		if len(m.Fields) == 0 {
+			continue
+		}
+		b.WriteString("\n| Field | Type |\n|---|---|\n")
+		for _, f := range m.Fields {
+			fmt.Fprintf(&b, "| `%s` | %s |\n", f.Name, f.Type)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -2,25 +2,119 @@ package network
 
 import (
 	"bufio"
-	"fmt"
-	"mmorpg/internal/game"
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"log"
 	"net"
 	"sync"
+	"time"
 )
 
+// Heartbeat lines of the TCP protocol. Browsers answer WebSocket pings
+// on their own, but TCP clients must reply to PING with PONG.
+var (
+	tcpPing = []byte(`{"type":"PING"}`)
+	tcpPong = "PONG"
+)
+
+// tcpCodec frames messages as newline-delimited lines
+type tcpCodec struct {
+	conn    net.Conn
+	scanner *bufio.Scanner
+	onPong  func()
+}
+
+func newTCPCodec(conn net.Conn) *tcpCodec {
+	return &tcpCodec{conn: conn, scanner: bufio.NewScanner(conn)}
+}
+
+func (c *tcpCodec) ReadFrame() ([]byte, error) {
+	for c.scanner.Scan() {
+		line := c.scanner.Bytes()
+		if isPong(line) {
+			if c.onPong != nil {
+				c.onPong()
+			}
+			continue
+		}
+		return line, nil
+	}
+	if err := c.scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return nil, ErrFrameTooLarge
+		}
+		return nil, err
+	}
+	return nil, net.ErrClosed
+}
+
+func (c *tcpCodec) SetReadLimit(n int) {
+	c.scanner.Buffer(make([]byte, 0, min(n, 4096)), n)
+}
+
+func isPong(line []byte) bool {
+	if !bytes.Contains(line, []byte(tcpPong)) {
+		return false
+	}
+	var msg struct {
+		Type string `json:"type"`
+	}
+	return json.Unmarshal(line, &msg) == nil && msg.Type == tcpPong
+}
+
+func (c *tcpCodec) WriteFrame(b []byte) error {
+	_, err := c.conn.Write(append(b, '\n'))
+	return err
+}
+
+func (c *tcpCodec) Ping() error {
+	return c.WriteFrame(tcpPing)
+}
+
+func (c *tcpCodec) SetPongHandler(fn func()) {
+	c.onPong = fn
+}
+
+func (c *tcpCodec) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+func (c *tcpCodec) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
+
+func (c *tcpCodec) Close(reason string) error {
+	return c.conn.Close()
+}
+
+func (c *tcpCodec) RemoteAddr() string {
+	return c.conn.RemoteAddr().String()
+}
+
+func (c *tcpCodec) Transport() string {
+	return "tcp"
+}
+
 type Server struct {
+	// TLSConfig, if set before Start, makes the server accept TLS only
+	TLSConfig *tls.Config
+
 	listenAddr string
 	ln         net.Listener
+	lnMu       sync.Mutex
 	quitch     chan struct{}
+	quitOnce   sync.Once
 	wg         sync.WaitGroup
-	game       *game.Game
+	hub        *Hub
 }
 
-func NewServer(listenAddr string, g *game.Game) *Server {
+func NewServer(listenAddr string, hub *Hub) *Server {
 	return &Server{
 		listenAddr: listenAddr,
 		quitch:     make(chan struct{}),
-		game:       g,
+		hub:        hub,
 	}
 }
 
@@ -30,9 +124,15 @@ func (s *Server) Start() error {
 		return err
 	}
 	defer ln.Close()
+	if s.TLSConfig != nil {
+		ln = tls.NewListener(ln, s.TLSConfig)
+	}
+
+	s.lnMu.Lock()
 	s.ln = ln
+	s.lnMu.Unlock()
 
-	fmt.Printf("Server running on %s\n", s.listenAddr)
+	log.Printf("TCP server running on %s", s.listenAddr)
 
 	go s.acceptLoop()
 
@@ -48,7 +148,7 @@ func (s *Server) acceptLoop() {
 			case <-s.quitch:
 				return
 			default:
-				fmt.Printf("accept error: %s\n", err)
+				log.Printf("Accept error: %v", err)
 				continue
 			}
 		}
@@ -59,28 +159,39 @@ func (s *Server) acceptLoop() {
 }
 
 func (s *Server) handleConnection(conn net.Conn) {
-	defer func() {
-		conn.Close()
-		s.wg.Done()
-	}()
+	defer s.wg.Done()
 
-	fmt.Printf("new connection from %s\n", conn.RemoteAddr())
+	s.hub.Serve(newTCPCodec(conn))
+}
 
-	player := s.game.AddPlayer(conn)
-	defer s.game.RemovePlayer(player.ID)
+// Addr is the listening address, or nil before Start has bound it
+func (s *Server) Addr() net.Addr {
+	s.lnMu.Lock()
+	defer s.lnMu.Unlock()
 
-	scanner := bufio.NewScanner(conn)
-	for scanner.Scan() {
-		HandleCommand(player, scanner.Text())
+	if s.ln == nil {
+		return nil
 	}
+	return s.ln.Addr()
+}
+
+// StopAccepting closes the listener but leaves open connections running
+func (s *Server) StopAccepting() {
+	s.quitOnce.Do(func() {
+		close(s.quitch)
+	})
 
-	if err := scanner.Err(); err != nil {
-		fmt.Printf("connection error: %v\n", err)
+	s.lnMu.Lock()
+	defer s.lnMu.Unlock()
+	if s.ln != nil {
+		s.ln.Close()
 	}
 }
 
+// Stop closes the listener and every open session, then waits for the
+// connection handlers to finish
 func (s *Server) Stop() {
-	close(s.quitch)
-	s.ln.Close()
+	s.StopAccepting()
+	s.hub.Shutdown()
 	s.wg.Wait()
 }
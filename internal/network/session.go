@@ -0,0 +1,387 @@
+package network
+
+import (
+	"errors"
+	"log"
+	"mmorpg/internal/game"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Close reasons recorded on sessions and logged when they end
+const (
+	CloseClientDisconnect = "client disconnect"
+	CloseServerShutdown   = "server shutdown"
+	CloseReadTimeout      = "read timeout" // no frames or pongs, the connection is likely dead
+	CloseAFK              = "afk"          // connected but no commands
+	CloseSendQueueFull    = "send queue full"
+	CloseWriteTimeout     = "write timeout"
+	CloseWriteError       = "write error"
+	CloseFrameTooLarge    = "frame too large"
+	CloseRateLimited      = "rate limited"
+	CloseBanned           = "banned"
+	CloseIncompatible     = "incompatible client"
+)
+
+// ErrFrameTooLarge is returned by ReadFrame for frames over the read limit
+var ErrFrameTooLarge = errors.New("frame too large")
+
+// Codec is the framing of one transport. Adding a transport means writing a
+// Codec and handing it to Hub.Serve.
+type Codec interface {
+	ReadFrame() ([]byte, error)
+	WriteFrame([]byte) error
+	// Ping sends a heartbeat the peer must answer. Answers are not returned
+	// by ReadFrame but reported to the pong handler.
+	Ping() error
+	SetPongHandler(func())
+	SetReadDeadline(time.Time) error
+	SetWriteDeadline(time.Time) error
+	// SetReadLimit caps the size of incoming frames. It is called before
+	// the first ReadFrame.
+	SetReadLimit(bytes int)
+	// Close ends the connection, telling the peer why if the protocol can
+	Close(reason string) error
+	RemoteAddr() string
+	Transport() string
+}
+
+// SessionConfig holds connection timings and limits. Zero values disable
+// the corresponding check.
+type SessionConfig struct {
+	PingInterval time.Duration // how often heartbeats are sent
+	ReadTimeout  time.Duration // close sessions with no frames or pongs for this long
+	WriteTimeout time.Duration // max time for a single write
+	AFKTimeout   time.Duration // close sessions sending no commands for this long
+	SendQueue    int           // outgoing messages buffered per session
+
+	MaxFrameSize int                  // largest incoming frame in bytes
+	RateLimits   map[string]RateLimit // per message type, see DefaultRateLimitKey
+	// Messages over the rate limit are dropped. A session dropping
+	// KickAfter of them within 10s is kicked, and a host kicked BanAfter
+	// times within BanDuration is banned for BanDuration.
+	KickAfter   int
+	BanAfter    int
+	BanDuration time.Duration
+}
+
+func DefaultSessionConfig() SessionConfig {
+	return SessionConfig{
+		PingInterval: 15 * time.Second,
+		ReadTimeout:  45 * time.Second,
+		WriteTimeout: 10 * time.Second,
+		AFKTimeout:   15 * time.Minute,
+		SendQueue:    256,
+
+		MaxFrameSize: 4096,
+		RateLimits:   DefaultRateLimits(),
+		KickAfter:    50,
+		BanAfter:     3,
+		BanDuration:  10 * time.Minute,
+	}
+}
+
+// Session is one client connection on any transport. It satisfies
+// game.Connection; writes are queued and sent by a dedicated goroutine so a
+// slow client never blocks the game loop.
+type Session struct {
+	ID         uint64
+	RemoteAddr string
+	Transport  string
+
+	hub        *Hub
+	codec      Codec
+	cfg        SessionConfig
+	limits     *limiter // only used by the read loop
+	sendq      chan []byte
+	closed     chan struct{}
+	lastActive atomic.Int64 // unix nanos of the last command
+
+	closeOnce sync.Once
+	reason    string
+}
+
+// Write queues b for sending. A client that can't keep up is disconnected
+// rather than allowed to grow the queue.
+func (s *Session) Write(b []byte) (int, error) {
+	msg := make([]byte, len(b))
+	copy(msg, b)
+
+	select {
+	case <-s.closed:
+		return 0, nil
+	default:
+	}
+
+	select {
+	case s.sendq <- msg:
+	default:
+		// Closing may block on the network, and Write is called with game
+		// locks held
+		go s.CloseWithReason(CloseSendQueueFull)
+	}
+	return len(b), nil
+}
+
+func (s *Session) Close() error {
+	s.CloseWithReason(CloseClientDisconnect)
+	return nil
+}
+
+// CloseWithReason closes the session. Only the first reason is kept.
+func (s *Session) CloseWithReason(reason string) {
+	s.closeOnce.Do(func() {
+		s.reason = reason
+		close(s.closed)
+		s.codec.Close(reason)
+	})
+}
+
+// Reason is why the session closed, empty while it is open
+func (s *Session) Reason() string {
+	select {
+	case <-s.closed:
+		return s.reason
+	default:
+		return ""
+	}
+}
+
+// writeLoop sends queued messages and heartbeats, and kicks AFK players
+func (s *Session) writeLoop() {
+	var tick, afk <-chan time.Time
+	if s.cfg.PingInterval > 0 {
+		ticker := time.NewTicker(s.cfg.PingInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	// The AFK check has its own timer so it works without heartbeats
+	var afkTimer *time.Timer
+	if s.cfg.AFKTimeout > 0 {
+		afkTimer = time.NewTimer(s.cfg.AFKTimeout)
+		defer afkTimer.Stop()
+		afk = afkTimer.C
+	}
+
+	for {
+		var err error
+		select {
+		case <-s.closed:
+			return
+		case msg := <-s.sendq:
+			s.setWriteDeadline()
+			err = s.codec.WriteFrame(msg)
+		case <-tick:
+			s.setWriteDeadline()
+			err = s.codec.Ping()
+		case now := <-afk:
+			deadline := time.Unix(0, s.lastActive.Load()).Add(s.cfg.AFKTimeout)
+			if !now.Before(deadline) {
+				s.CloseWithReason(CloseAFK)
+				return
+			}
+			afkTimer.Reset(deadline.Sub(now))
+		}
+
+		if err != nil {
+			if isTimeout(err) {
+				s.CloseWithReason(CloseWriteTimeout)
+			} else {
+				s.CloseWithReason(CloseWriteError)
+			}
+			return
+		}
+	}
+}
+
+// allow applies the rate limit for a message of type typ. Dropped messages
+// of non-silent limits count towards a kick, and kicks towards a ban of the
+// host.
+func (s *Session) allow(typ string, now time.Time) bool {
+	ok, violation := s.limits.allow(typ, now)
+	if ok || !violation {
+		return ok
+	}
+	if n := s.limits.violate(now); s.cfg.KickAfter > 0 && n >= s.cfg.KickAfter {
+		s.CloseWithReason(CloseRateLimited)
+		host := hostOf(s.RemoteAddr)
+		if s.hub.bans.kick(host, now, s.cfg.BanAfter, s.cfg.BanDuration) {
+			log.Printf("Banned %s for %v after repeated rate limit kicks", host, s.cfg.BanDuration)
+		}
+	}
+	return false
+}
+
+func (s *Session) closeOnReadError(err error) {
+	switch {
+	case isTimeout(err):
+		s.CloseWithReason(CloseReadTimeout)
+	case errors.Is(err, ErrFrameTooLarge):
+		s.CloseWithReason(CloseFrameTooLarge)
+	default:
+		s.CloseWithReason(CloseClientDisconnect)
+	}
+}
+
+func (s *Session) setWriteDeadline() {
+	if s.cfg.WriteTimeout > 0 {
+		s.codec.SetWriteDeadline(time.Now().Add(s.cfg.WriteTimeout))
+	}
+}
+
+func (s *Session) extendReadDeadline() {
+	if s.cfg.ReadTimeout > 0 {
+		s.codec.SetReadDeadline(time.Now().Add(s.cfg.ReadTimeout))
+	}
+}
+
+// Hub runs the session lifecycle for every transport and dispatches
+// incoming frames to the game
+type Hub struct {
+	game *game.Game
+	cfg  SessionConfig
+
+	lastID   atomic.Uint64
+	mu       sync.Mutex
+	sessions map[uint64]*Session
+	closing  bool
+	bans     *banList
+}
+
+func NewHub(g *game.Game, cfg SessionConfig) *Hub {
+	if cfg.SendQueue <= 0 {
+		cfg.SendQueue = DefaultSessionConfig().SendQueue
+	}
+	return &Hub{
+		game:     g,
+		cfg:      cfg,
+		sessions: make(map[uint64]*Session),
+		bans:     newBanList(),
+	}
+}
+
+// Banned reports whether connections from addr are currently refused
+func (h *Hub) Banned(addr string) bool {
+	return h.bans.isBanned(hostOf(addr), time.Now())
+}
+
+// Serve runs a connection until it closes: the client says HELLO, the
+// player joins, frames are dispatched to Handlers, and the player leaves.
+// Clients that skip HELLO join with protocol version 1.
+func (h *Hub) Serve(codec Codec) {
+	s := &Session{
+		ID:         h.lastID.Add(1),
+		RemoteAddr: codec.RemoteAddr(),
+		Transport:  codec.Transport(),
+		codec:      codec,
+		hub:        h,
+		cfg:        h.cfg,
+		limits:     newLimiter(h.cfg.RateLimits),
+		sendq:      make(chan []byte, h.cfg.SendQueue),
+		closed:     make(chan struct{}),
+	}
+	s.lastActive.Store(time.Now().UnixNano())
+	codec.SetPongHandler(s.extendReadDeadline)
+	if h.cfg.MaxFrameSize > 0 {
+		codec.SetReadLimit(h.cfg.MaxFrameSize)
+	}
+
+	if h.Banned(s.RemoteAddr) {
+		log.Printf("Refused banned %s %s", s.Transport, s.RemoteAddr)
+		codec.Close(CloseBanned)
+		return
+	}
+
+	h.mu.Lock()
+	if h.closing {
+		h.mu.Unlock()
+		codec.Close(CloseServerShutdown)
+		return
+	}
+	h.sessions[s.ID] = s
+	h.mu.Unlock()
+
+	defer func() {
+		h.mu.Lock()
+		delete(h.sessions, s.ID)
+		h.mu.Unlock()
+	}()
+
+	log.Printf("Session %d opened (%s %s)", s.ID, s.Transport, s.RemoteAddr)
+	version, pending := s.handshake()
+	if version == 0 {
+		log.Printf("Session %d closed (%s %s): %s", s.ID, s.Transport, s.RemoteAddr, s.Reason())
+		return
+	}
+	go s.writeLoop()
+
+	player := h.game.JoinPlayer(s, version)
+	defer func() {
+		h.game.DisconnectPlayer(player.ID, s, !kicked(s.Reason()))
+	}()
+
+	for {
+		var frame []byte
+		var err error
+		if pending != nil {
+			// A legacy client's first read was started by the handshake
+			r := <-pending
+			frame, err, pending = r.frame, r.err, nil
+		} else {
+			s.extendReadDeadline()
+			frame, err = codec.ReadFrame()
+		}
+		if err != nil {
+			s.closeOnReadError(err)
+			break
+		}
+
+		s.lastActive.Store(time.Now().UnixNano())
+		c := &Context{Player: player, Session: s}
+		err = Handlers.Dispatch(c, frame)
+		player = c.Player
+		if err != nil && err != ErrUnknownMessage && err != ErrRateLimited {
+			log.Printf("Session %d: %v", s.ID, err)
+		}
+	}
+
+	log.Printf("Session %d closed (player %d, %s %s): %s", s.ID, player.ID, s.Transport, s.RemoteAddr, s.Reason())
+}
+
+// Closing reports whether Shutdown has been called
+func (h *Hub) Closing() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.closing
+}
+
+// Shutdown refuses new sessions and closes the open ones. It returns how
+// many were closed and is safe to call more than once.
+func (h *Hub) Shutdown() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closing = true
+	for _, s := range h.sessions {
+		s.CloseWithReason(CloseServerShutdown)
+	}
+	return len(h.sessions)
+}
+
+// kicked reports whether the server ended the session on purpose, in which
+// case the player doesn't get to reconnect
+func kicked(reason string) bool {
+	switch reason {
+	case CloseServerShutdown, CloseAFK, CloseFrameTooLarge, CloseRateLimited, CloseBanned, CloseIncompatible:
+		return true
+	}
+	return false
+}
+
+func isTimeout(err error) bool {
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
@@ -1,65 +1,163 @@
 package network
 
 import (
+	"errors"
 	"log"
-	"mmorpg/internal/game"
 	"net/http"
-	"sync"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
 
-var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool { return true },
+// wsCodec frames messages as WebSocket text messages
+type wsCodec struct {
+	conn          *websocket.Conn
+	writeDeadline time.Time
+	compressed    bool // permessage-deflate was negotiated
 }
 
-// WSConnection wraps websocket.Conn to satisfy game.Connection interface
-type WSConnection struct {
-	conn *websocket.Conn
-	mu   sync.Mutex
+func (c *wsCodec) Compressed() bool {
+	return c.compressed
 }
 
-func (w *WSConnection) Write(b []byte) (int, error) {
-	w.mu.Lock()
-	defer w.mu.Unlock()
-	err := w.conn.WriteMessage(websocket.TextMessage, b)
-	if err != nil {
-		return 0, err
+func (c *wsCodec) ReadFrame() ([]byte, error) {
+	_, msg, err := c.conn.ReadMessage()
+	if errors.Is(err, websocket.ErrReadLimit) {
+		return nil, ErrFrameTooLarge
+	}
+	return msg, err
+}
+
+func (c *wsCodec) SetReadLimit(n int) {
+	c.conn.SetReadLimit(int64(n))
+}
+
+func (c *wsCodec) WriteFrame(b []byte) error {
+	return c.conn.WriteMessage(websocket.TextMessage, b)
+}
+
+// Ping sends a control frame; browsers answer it without client code
+func (c *wsCodec) Ping() error {
+	return c.conn.WriteControl(websocket.PingMessage, nil, c.writeDeadline)
+}
+
+func (c *wsCodec) SetPongHandler(fn func()) {
+	c.conn.SetPongHandler(func(string) error {
+		fn()
+		return nil
+	})
+}
+
+func (c *wsCodec) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline is only called from the session's writer goroutine
+func (c *wsCodec) SetWriteDeadline(t time.Time) error {
+	c.writeDeadline = t
+	return c.conn.SetWriteDeadline(t)
+}
+
+// Close sends a close frame first so the client can tell a shutdown or kick
+// from a dropped connection
+func (c *wsCodec) Close(reason string) error {
+	code := websocket.CloseNormalClosure
+	switch reason {
+	case CloseServerShutdown:
+		code = websocket.CloseGoingAway
+	case CloseSendQueueFull, CloseAFK, CloseRateLimited, CloseBanned, CloseIncompatible:
+		code = websocket.ClosePolicyViolation
+	case CloseFrameTooLarge:
+		code = websocket.CloseMessageTooBig
 	}
-	return len(b), nil
+	msg := websocket.FormatCloseMessage(code, reason)
+	c.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+	return c.conn.Close()
+}
+
+func (c *wsCodec) RemoteAddr() string {
+	return c.conn.RemoteAddr().String()
 }
 
-func (w *WSConnection) Close() error {
-	return w.conn.Close()
+func (c *wsCodec) Transport() string {
+	return "ws"
+}
+
+// WSConfig holds the HTTP side of the WebSocket server
+type WSConfig struct {
+	Path      string // WebSocket route
+	ClientDir string // static client files served at /
+
+	// AllowedOrigins lists the origins, such as "https://example.com", whose
+	// pages may connect. "*" allows any; empty allows only the page's own
+	// host. Requests without an Origin header come from non-browser clients
+	// and are always allowed.
+	AllowedOrigins []string
+	// Compression negotiates permessage-deflate with clients that offer it
+	Compression bool
 }
 
 type WSServer struct {
-	game *game.Game
+	hub      *Hub
+	cfg      WSConfig
+	upgrader websocket.Upgrader
+}
+
+func NewWSServer(hub *Hub, cfg WSConfig) *WSServer {
+	s := &WSServer{hub: hub, cfg: cfg}
+	s.upgrader = websocket.Upgrader{
+		CheckOrigin:       s.checkOrigin,
+		EnableCompression: cfg.Compression,
+	}
+	return s
+}
+
+func (s *WSServer) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if len(s.cfg.AllowedOrigins) == 0 {
+		u, err := url.Parse(origin)
+		return err == nil && strings.EqualFold(u.Host, r.Host)
+	}
+	for _, allowed := range s.cfg.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	log.Printf("Rejected WebSocket from origin %s (%s)", origin, r.RemoteAddr)
+	return false
 }
 
-func NewWSServer(g *game.Game) *WSServer {
-	return &WSServer{game: g}
+// Handler serves the web client and the WebSocket route
+func (s *WSServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/", http.FileServer(http.Dir(s.cfg.ClientDir)))
+	mux.HandleFunc(s.cfg.Path, s.HandleWS)
+	return mux
 }
 
 func (s *WSServer) HandleWS(w http.ResponseWriter, r *http.Request) {
-	conn, err := upgrader.Upgrade(w, r, nil)
+	if s.hub.Closing() {
+		http.Error(w, "server shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	if s.hub.Banned(r.RemoteAddr) {
+		http.Error(w, "banned", http.StatusForbidden)
+		return
+	}
+
+	conn, err := s.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("Upgrade error: %v", err)
 		return
 	}
 
-	wsConn := &WSConnection{conn: conn}
-	player := s.game.AddPlayer(wsConn)
-	defer s.game.RemovePlayer(player.ID)
-	defer wsConn.Close()
-
-	for {
-		_, msg, err := conn.ReadMessage()
-		if err != nil {
-			log.Printf("Player %d disconnected: %v", player.ID, err)
-			break
-		}
-
-		HandleCommand(player, string(msg))
-	}
+	// The upgrader accepts deflate whenever the client offers it
+	compressed := s.cfg.Compression &&
+		strings.Contains(r.Header.Get("Sec-WebSocket-Extensions"), "permessage-deflate")
+	s.hub.Serve(&wsCodec{conn: conn, compressed: compressed})
 }
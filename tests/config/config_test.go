@@ -0,0 +1,103 @@
+package config_test
+
+import (
+	"mmorpg/internal/config"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoad_Precedence(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "server.json")
+	os.WriteFile(path, []byte(`{
+		"listen": "127.0.0.1:7000",
+		"ws_path": "/game",
+		"game": {"inventory_size": 30, "item_expiry": "5m"}
+	}`), 0644)
+	t.Setenv("MMORPG_LISTEN", "127.0.0.1:7100")
+
+	cfg, err := config.Load([]string{"-config", path, "-client-dir", dir, "-tick-rate", "20"})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	if cfg.Listen != "127.0.0.1:7100" {
+		t.Errorf("Expected env to override file listen, got %s", cfg.Listen)
+	}
+	if cfg.WSPath != "/game" {
+		t.Errorf("Expected ws_path from file, got %s", cfg.WSPath)
+	}
+	if cfg.Game.InventorySize != 30 || cfg.Game.ItemExpiry != 5*time.Minute {
+		t.Errorf("Expected game settings from file, got %+v", cfg.Game)
+	}
+	if cfg.Game.GoldValue != 100 {
+		t.Errorf("Expected unset gold_value to keep its default, got %d", cfg.Game.GoldValue)
+	}
+	if cfg.Game.TickRate != 20 {
+		t.Errorf("Expected flag tick rate 20, got %d", cfg.Game.TickRate)
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, err := config.Load([]string{"-client-dir", dir, "-tick-rate", "-5"}); err == nil {
+		t.Error("Expected negative tick rate to be rejected")
+	}
+	if _, err := config.Load([]string{"-client-dir", dir, "-ws-path", "ws"}); err == nil {
+		t.Error("Expected ws path without leading slash to be rejected")
+	}
+	if _, err := config.Load([]string{"-client-dir", filepath.Join(dir, "missing")}); err == nil {
+		t.Error("Expected missing client dir to be rejected")
+	}
+}
+
+func TestLoad_RateLimitsMerge(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "server.json")
+	os.WriteFile(path, []byte(`{"rate_limits": {"MOVE": {"rate": 50, "burst": 60}}}`), 0644)
+
+	cfg, err := config.Load([]string{"-config", path, "-client-dir", dir})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if l := cfg.RateLimits["MOVE"]; l.Rate != 50 || l.Burst != 60 {
+		t.Errorf("Expected MOVE limit from file, got %+v", l)
+	}
+	if _, ok := cfg.RateLimits["MARKET_BUY"]; !ok {
+		t.Error("Expected other default limits to be kept")
+	}
+
+	os.WriteFile(path, []byte(`{"rate_limits": {"MOVE": {"rate": 0, "burst": 1}}}`), 0644)
+	if _, err := config.Load([]string{"-config", path, "-client-dir", dir}); err == nil {
+		t.Error("Expected a zero rate to be rejected")
+	}
+}
+
+func TestLoad_TLSAndOrigins(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, err := config.Load([]string{"-client-dir", dir, "-tls-cert", "cert.pem"}); err == nil {
+		t.Error("Expected a certificate without a key to be rejected")
+	}
+	if _, err := config.Load([]string{"-client-dir", dir, "-tls-cert", "c.pem", "-tls-key", "k.pem", "-tls-self-signed"}); err == nil {
+		t.Error("Expected self-signed mode with a certificate to be rejected")
+	}
+
+	path := filepath.Join(dir, "server.json")
+	os.WriteFile(path, []byte(`{"allowed_origins": ["game.example"]}`), 0644)
+	if _, err := config.Load([]string{"-config", path, "-client-dir", dir}); err == nil {
+		t.Error("Expected an origin without a scheme to be rejected")
+	}
+
+	os.WriteFile(path, []byte(`{"allowed_origins": ["https://game.example", "*"]}`), 0644)
+	cfg, err := config.Load([]string{"-config", path, "-client-dir", dir, "-tls-self-signed"})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !cfg.TLSSelfSigned || len(cfg.AllowedOrigins) != 2 {
+		t.Errorf("Expected TLS and origins from flags and file, got %+v", cfg)
+	}
+}
@@ -0,0 +1,47 @@
+package game_test
+
+import (
+	"mmorpg/internal/game"
+	"testing"
+)
+
+func TestChannel_OverflowOpensNewChannel(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	g.GetMap("town").ChannelCap = 2
+
+	a := g.AddPlayer(nil)
+	b := g.AddPlayer(nil)
+	c := g.AddPlayer(nil)
+
+	if a.MapID != "town" || b.MapID != "town" {
+		t.Errorf("Expected first two players on town, got %s and %s", a.MapID, b.MapID)
+	}
+	if c.MapID != "town@2" {
+		t.Fatalf("Expected third player on town@2, got %s", c.MapID)
+	}
+	if len(g.GetMap("town@2").NPCs) != len(g.GetMap("town").NPCs) {
+		t.Error("Expected the new channel to have the town NPCs")
+	}
+}
+
+func TestChannel_Switch(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	g.GetMap("town").ChannelCap = 1
+
+	a := g.AddPlayer(nil)
+	b := g.AddPlayer(nil)
+	if b.MapID != "town@2" {
+		t.Fatalf("Expected second player on town@2, got %s", b.MapID)
+	}
+
+	// The cap is soft, so joining a friend on a full channel is allowed
+	g.SwitchChannel(b, 1)
+	if b.MapID != a.MapID {
+		t.Errorf("Expected switch to channel 1, got %s", b.MapID)
+	}
+
+	g.SwitchChannel(b, 7)
+	if b.MapID != "town" {
+		t.Errorf("Expected unknown channel to be ignored, got %s", b.MapID)
+	}
+}
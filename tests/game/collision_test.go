@@ -0,0 +1,87 @@
+package game_test
+
+import (
+	"mmorpg/internal/game"
+	"testing"
+	"time"
+)
+
+func TestCollisionGrid_Rasterize(t *testing.T) {
+	c := game.NewCollisionGrid(200, 200, []game.Wall{{X: 40, Y: 40, W: 40, H: 20}})
+
+	if !c.Blocked(50, 50) || !c.Blocked(79, 59) {
+		t.Error("Expected points inside the wall to be blocked")
+	}
+	if c.Blocked(30, 50) || c.Blocked(50, 65) {
+		t.Error("Expected points outside the wall to be open")
+	}
+	if !c.SegmentBlocked(0, 50, 150, 50) {
+		t.Error("Expected segment through the wall to be blocked")
+	}
+}
+
+func TestCollisionGrid_Slide(t *testing.T) {
+	c := game.NewCollisionGrid(200, 200, []game.Wall{{X: 100, Y: 0, W: 20, H: 200}})
+
+	x, y := c.Slide(90, 50, 110, 60)
+	if x != 90 || y != 60 {
+		t.Errorf("Expected to slide along the wall to (90, 60), got (%.0f, %.0f)", x, y)
+	}
+}
+
+func TestMovePlayer_BlockedByWall(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	p := g.AddPlayer(nil)
+	town := g.GetMap("town")
+	town.SetWalls([]game.Wall{{X: 420, Y: 200, W: 40, H: 200}})
+
+	g.MovePlayer(p, 440, 300)
+	if p.X != 400 || p.Y != 300 {
+		t.Errorf("Expected player to stay at (400, 300), got (%.0f, %.0f)", p.X, p.Y)
+	}
+
+	g.MovePlayer(p, 380, 310)
+	if p.X != 380 || p.Y != 310 {
+		t.Errorf("Expected open move to succeed, got (%.0f, %.0f)", p.X, p.Y)
+	}
+}
+
+func TestProjectile_StoppedByWall(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	field := g.GetMap("field")
+	field.SetWalls([]game.Wall{{X: 100, Y: 0, W: 20, H: 600}})
+
+	field.AddProjectile(&game.Projectile{ID: 1, X: 50, Y: 300, VX: 1, Type: game.ProjectileTypeDefault})
+	for i := 0; i < 10; i++ {
+		field.UpdateProjectiles(time.Now(), 1.0/30)
+	}
+	if len(field.Projectiles) != 0 {
+		t.Error("Expected projectile to be removed at the wall")
+	}
+}
+
+func TestSpawnMonster_FullyWalledMap(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	field := g.GetMap("field")
+	for id := range field.Monsters {
+		delete(field.Monsters, id)
+	}
+
+	// One open tile in the corner, outside the usual spawn area
+	field.SetWalls([]game.Wall{{X: 20, Y: 0, W: field.Width, H: field.Height}, {X: 0, Y: 20, W: 20, H: field.Height}})
+	field.SpawnMonster()
+	if len(field.Monsters) != 1 {
+		t.Fatal("Expected a monster on the only open tile")
+	}
+	for _, mon := range field.Monsters {
+		if mon.X != 10 || mon.Y != 10 {
+			t.Errorf("Expected spawn at (10, 10), got (%.0f, %.0f)", mon.X, mon.Y)
+		}
+	}
+
+	field.SetWalls([]game.Wall{{X: 0, Y: 0, W: field.Width, H: field.Height}})
+	field.SpawnMonster()
+	if len(field.Monsters) != 1 {
+		t.Error("Expected no spawn on a map without open tiles")
+	}
+}
@@ -0,0 +1,86 @@
+package game_test
+
+import (
+	"mmorpg/internal/game"
+	"sync"
+	"testing"
+)
+
+func TestEnqueue_AppliedOnTickInOrder(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	p := g.AddPlayer(nil)
+
+	var order []int
+	for i := 1; i <= 3; i++ {
+		i := i
+		g.Enqueue(p, func() { order = append(order, i) })
+	}
+	if len(order) != 0 {
+		t.Fatal("Expected commands to wait for the tick")
+	}
+
+	g.Update()
+	if len(order) != 3 || order[0] != 1 || order[1] != 2 || order[2] != 3 {
+		t.Errorf("Expected commands in arrival order, got %v", order)
+	}
+
+	g.Update()
+	if len(order) != 3 {
+		t.Errorf("Expected each command to run once, got %v", order)
+	}
+}
+
+func TestEnqueue_DroppedForRemovedPlayer(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	p := g.AddPlayer(nil)
+
+	ran := false
+	g.Enqueue(p, func() { ran = true })
+	g.RemovePlayer(p.ID)
+	g.Update()
+
+	if ran {
+		t.Error("Expected the command of a removed player to be dropped")
+	}
+}
+
+func TestEnqueue_DroppedForHeldPlayer(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	conn := &recConn{}
+	p := g.AddPlayer(conn)
+	x := p.X
+
+	g.Enqueue(p, func() { g.MovePlayer(p, x+10, p.Y) })
+	g.DisconnectPlayer(p.ID, conn, true)
+	g.Update()
+
+	if p.X != x {
+		t.Error("Expected a held player's queued MOVE to be dropped")
+	}
+}
+
+func TestEnqueue_ConcurrentWithTicks(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	p := g.AddPlayer(nil)
+	x := p.X
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				g.Enqueue(p, func() { g.MovePlayer(p, p.X+1, p.Y) })
+			}
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		g.Update()
+	}
+	wg.Wait()
+	g.Update()
+
+	if p.X != x+200 {
+		t.Errorf("Expected all 200 moves applied, moved %.0f", p.X-x)
+	}
+}
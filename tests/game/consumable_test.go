@@ -0,0 +1,65 @@
+package game_test
+
+import (
+	"mmorpg/internal/game"
+	"testing"
+)
+
+func TestConsumable_PotionHealsWithCooldown(t *testing.T) {
+	p := game.NewPlayer(1, nil, nil)
+	potion := game.NewConsumable("potion_small", 3)
+	potion.ID = 1
+	p.Inventory = append(p.Inventory, potion)
+	p.HP = 10
+
+	p.UseItem(1)
+	if p.HP != 40 {
+		t.Errorf("Expected HP 40 after potion, got %d", p.HP)
+	}
+	if potion.Count != 2 {
+		t.Errorf("Expected 2 potions left, got %d", potion.Count)
+	}
+
+	p.UseItem(1)
+	if p.HP != 40 || potion.Count != 2 {
+		t.Error("Expected second use to be blocked by cooldown")
+	}
+}
+
+func TestConsumable_SpeedBuff(t *testing.T) {
+	p := game.NewPlayer(1, nil, nil)
+	elixir := game.NewConsumable("elixir_speed", 1)
+	elixir.ID = 7
+	p.Inventory = append(p.Inventory, elixir)
+
+	p.SetHotbar(0, 7)
+	p.UseHotbar(0)
+
+	if p.Speed != 7.0 {
+		t.Errorf("Expected speed 7.0 with buff, got %.2f", p.Speed)
+	}
+	if len(p.Inventory) != 0 {
+		t.Errorf("Expected empty stack to be removed, got %d items", len(p.Inventory))
+	}
+	if len(p.Buffs) != 1 {
+		t.Errorf("Expected 1 active buff, got %d", len(p.Buffs))
+	}
+}
+
+func TestConsumable_SellStackPaysPerUnit(t *testing.T) {
+	p := game.NewPlayer(1, nil, nil)
+	potions := game.NewConsumable("potion_small", 4)
+	potions.ID = 1
+	single := game.NewConsumable("potion_small", 1)
+	single.ID = 2
+	p.Inventory = append(p.Inventory, potions, single)
+
+	p.Sell(1)
+	if p.Gold != 40 {
+		t.Errorf("Expected 40 gold for a stack of 4, got %d", p.Gold)
+	}
+	p.Sell(2)
+	if p.Gold != 50 {
+		t.Errorf("Expected 10 more gold for a single potion, got %d", p.Gold)
+	}
+}
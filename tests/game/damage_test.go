@@ -0,0 +1,59 @@
+package game_test
+
+import (
+	"mmorpg/internal/game"
+	"testing"
+)
+
+func fixedDamageConfig() *game.DamageConfig {
+	c := game.DefaultDamageConfig()
+	c.CritChance = 0
+	c.MinRoll = 1
+	c.MaxRoll = 1
+	return c
+}
+
+func TestDamage_ElementMatrix(t *testing.T) {
+	c := fixedDamageConfig()
+
+	cases := []struct {
+		attacker, defender game.Element
+		want               int
+	}{
+		{game.ElementFire, game.ElementGrass, 20},
+		{game.ElementFire, game.ElementWater, 5},
+		{game.ElementFire, game.ElementFire, 10},
+		{game.ElementNeutral, game.ElementWater, 10},
+	}
+
+	for _, tc := range cases {
+		got := c.Roll(10, tc.attacker, tc.defender, 0).Amount
+		if got != tc.want {
+			t.Errorf("Roll(10, %d, %d) = %d, want %d", tc.attacker, tc.defender, got, tc.want)
+		}
+	}
+}
+
+func TestDamage_CritAndMinimum(t *testing.T) {
+	c := fixedDamageConfig()
+	c.CritChance = 1
+
+	roll := c.Roll(10, game.ElementNeutral, game.ElementNeutral, 0)
+	if !roll.Crit || roll.Amount != 15 {
+		t.Errorf("Expected crit for 15, got crit=%v amount=%d", roll.Crit, roll.Amount)
+	}
+
+	roll = c.Roll(10, game.ElementNeutral, game.ElementNeutral, 100)
+	if roll.Amount != 1 {
+		t.Errorf("Expected minimum damage 1, got %d", roll.Amount)
+	}
+}
+
+func TestDamage_MonsterTypeElements(t *testing.T) {
+	if game.MonsterTypeWater.Element() != game.ElementWater {
+		t.Error("Expected water monster to be water element")
+	}
+	if game.ProjectileTypeDefault.Element() != game.ElementNeutral {
+		t.Error("Expected default projectile to be neutral")
+	}
+}
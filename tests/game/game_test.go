@@ -6,7 +6,7 @@ import (
 )
 
 func TestNewGame(t *testing.T) {
-	g := game.NewGame()
+	g := game.NewGame(game.DefaultConfig())
 	if g == nil {
 		t.Fatal("NewGame returned nil")
 	}
@@ -15,8 +15,17 @@ func TestNewGame(t *testing.T) {
 	}
 }
 
+func TestNewGame_ZeroConfig(t *testing.T) {
+	g := game.NewGame(game.Config{})
+	p := g.AddPlayer(nil)
+	g.Update()
+	if g.GetPlayers()[p.ID] == nil {
+		t.Error("Expected a game from a zero Config to work")
+	}
+}
+
 func TestGame_AddRemovePlayer(t *testing.T) {
-	g := game.NewGame()
+	g := game.NewGame(game.DefaultConfig())
 
 	p := g.AddPlayer(nil)
 	if p == nil {
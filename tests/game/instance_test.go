@@ -0,0 +1,69 @@
+package game_test
+
+import (
+	"mmorpg/internal/game"
+	"strings"
+	"testing"
+	"time"
+)
+
+// walkIntoDungeon puts p on the field portal that leads to the dungeon
+func walkIntoDungeon(g *game.Game, p *game.Player) {
+	p.MapID = "field"
+	p.X, p.Y = 750, 300
+	p.LastPortalUse = time.Time{}
+	g.Update()
+}
+
+func TestInstance_PerPlayer(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	a := g.AddPlayer(nil)
+	b := g.AddPlayer(nil)
+
+	walkIntoDungeon(g, a)
+	walkIntoDungeon(g, b)
+
+	if !strings.HasPrefix(a.MapID, "dungeon#") {
+		t.Fatalf("Expected player to enter a dungeon instance, got %s", a.MapID)
+	}
+	if a.MapID == b.MapID {
+		t.Error("Expected solo players to get separate instances")
+	}
+	if len(g.GetMap(a.MapID).Monsters) == 0 {
+		t.Error("Expected the instance to be populated with monsters")
+	}
+}
+
+func TestInstance_SharedByParty(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	a := g.AddPlayer(nil)
+	b := g.AddPlayer(nil)
+	g.InviteToParty(a, b.ID)
+	g.AcceptParty(b, a.Party.ID)
+
+	walkIntoDungeon(g, a)
+	walkIntoDungeon(g, b)
+
+	if a.MapID != b.MapID {
+		t.Errorf("Expected party to share an instance, got %s and %s", a.MapID, b.MapID)
+	}
+	if g.Instances() != 1 {
+		t.Errorf("Expected 1 instance, got %d", g.Instances())
+	}
+}
+
+func TestInstance_Cap(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	var last *game.Player
+	for i := 0; i < 21; i++ {
+		last = g.AddPlayer(nil)
+		walkIntoDungeon(g, last)
+	}
+
+	if g.Instances() != 20 {
+		t.Errorf("Expected instance count capped at 20, got %d", g.Instances())
+	}
+	if last.MapID != "field" {
+		t.Errorf("Expected player over the cap to stay on the field, got %s", last.MapID)
+	}
+}
@@ -0,0 +1,65 @@
+package game_test
+
+import (
+	"mmorpg/internal/game"
+	"testing"
+)
+
+func TestNPC_BuyShopItem(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	p := g.AddPlayer(nil)
+	p.Inventory = p.Inventory[:0]
+	p.Gold = 120
+	p.Move(400, 250)
+
+	// Shopkeeper is NPC 1 in town, Wooden Sword is entry 1 at 50 gold
+	g.BuyShopItem(p, 1, 1)
+	g.BuyShopItem(p, 1, 1)
+	g.BuyShopItem(p, 1, 1)
+
+	if p.Gold != 20 {
+		t.Errorf("Expected 20 gold left, got %d", p.Gold)
+	}
+	if len(p.Inventory) != 2 {
+		t.Fatalf("Expected 2 items, got %d", len(p.Inventory))
+	}
+	if p.Inventory[0].ID == p.Inventory[1].ID {
+		t.Error("Expected bought items to have distinct IDs")
+	}
+}
+
+func TestNPC_BuyTooFar(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	p := g.AddPlayer(nil)
+	p.Inventory = p.Inventory[:0]
+	p.Gold = 100
+	p.Move(700, 500)
+
+	g.BuyShopItem(p, 1, 1)
+
+	if p.Gold != 100 || len(p.Inventory) != 0 {
+		t.Error("Expected purchase to be refused away from the shop")
+	}
+}
+
+func TestNPC_Dialog(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	p := g.AddPlayer(nil)
+	p.Move(300, 250)
+
+	// Town Guide is NPC 3
+	g.Interact(p, 3)
+	if p.Dialog == nil || p.Dialog.Node != "greet" {
+		t.Fatal("Expected dialog to start at greet")
+	}
+
+	g.ChooseDialogOption(p, 3, 0)
+	if p.Dialog == nil || p.Dialog.Node != "field" {
+		t.Fatal("Expected dialog to move to field")
+	}
+
+	g.ChooseDialogOption(p, 3, 1)
+	if p.Dialog != nil {
+		t.Error("Expected dialog to close")
+	}
+}
@@ -0,0 +1,61 @@
+package game_test
+
+import (
+	"mmorpg/internal/game"
+	"testing"
+)
+
+func TestFindPath_AroundWall(t *testing.T) {
+	c := game.NewCollisionGrid(200, 200, []game.Wall{{X: 80, Y: 0, W: 40, H: 160}})
+
+	path := c.FindPath(30, 30, 170, 30)
+	if len(path) == 0 {
+		t.Fatal("Expected a path around the wall")
+	}
+
+	x, y := 30.0, 30.0
+	for _, wp := range path {
+		if c.SegmentBlocked(x, y, wp.X, wp.Y) {
+			t.Fatalf("Path step (%.0f, %.0f) -> (%.0f, %.0f) crosses the wall", x, y, wp.X, wp.Y)
+		}
+		x, y = wp.X, wp.Y
+	}
+	if x != 170 || y != 30 {
+		t.Errorf("Expected path to end at the goal, got (%.0f, %.0f)", x, y)
+	}
+}
+
+func TestFindPath_Unreachable(t *testing.T) {
+	c := game.NewCollisionGrid(200, 200, []game.Wall{{X: 80, Y: 0, W: 40, H: 200}})
+
+	if path := c.FindPath(30, 30, 170, 30); path != nil {
+		t.Errorf("Expected no path through a full wall, got %d waypoints", len(path))
+	}
+}
+
+func TestUpdateMonsters_WalksAroundWall(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	p := g.AddPlayer(nil)
+	field := g.GetMap("field")
+	field.SetWalls([]game.Wall{{X: 380, Y: 200, W: 40, H: 200}})
+	for id := range field.Monsters {
+		delete(field.Monsters, id)
+	}
+
+	field.SpawnMonster()
+	var mon *game.Monster
+	for _, m := range field.Monsters {
+		mon = m
+	}
+	mon.X, mon.Y = 300, 300
+	p.MapID = "field"
+	p.X, p.Y = 500, 300
+
+	players := []*game.Player{p}
+	for i := 0; i < 300 && mon.X < 420; i++ {
+		field.UpdateMonsters(players, 1.0/30)
+	}
+	if mon.X < 420 {
+		t.Errorf("Expected monster to get past the wall, stuck at (%.0f, %.0f)", mon.X, mon.Y)
+	}
+}
@@ -26,3 +26,22 @@ func TestPlayer_Move(t *testing.T) {
 		t.Errorf("Expected Y 20.0, got %.2f", p.Y)
 	}
 }
+
+func TestConfig_InventorySize(t *testing.T) {
+	for _, size := range []int{5, 20, 21} {
+		cfg := game.DefaultConfig()
+		cfg.InventorySize = size
+		g := game.NewGame(cfg)
+		p := g.AddPlayer(nil)
+
+		town := g.GetMap("town")
+		item := game.NewConsumable("potion_small", 1)
+		item.ID, item.X, item.Y = 1, p.X, p.Y
+		town.Items[item.ID] = item
+		town.CheckCollisions([]*game.Player{p})
+
+		if len(p.Inventory) != size {
+			t.Errorf("Expected %d items with a %d-slot inventory, got %d", size, size, len(p.Inventory))
+		}
+	}
+}
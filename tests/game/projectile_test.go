@@ -0,0 +1,85 @@
+package game_test
+
+import (
+	"mmorpg/internal/game"
+	"testing"
+	"time"
+)
+
+func lineOfMonsters(field *game.WorldMap, n int) []*game.Monster {
+	for id := range field.Monsters {
+		delete(field.Monsters, id)
+	}
+	var mons []*game.Monster
+	for i := 0; i < n; i++ {
+		field.SpawnMonster()
+	}
+	for _, mon := range field.Monsters {
+		mon.X, mon.Y = 200+float64(len(mons))*60, 500
+		mon.MaxHP, mon.HP = 1000, 1000
+		mons = append(mons, mon)
+	}
+	return mons
+}
+
+func flyProjectile(g *game.Game, field *game.WorldMap, ticks int) {
+	players := []*game.Player{}
+	for _, p := range g.GetPlayers() {
+		players = append(players, p)
+	}
+	for i := 0; i < ticks; i++ {
+		field.UpdateProjectiles(time.Now(), 1.0/30)
+		field.CheckCollisions(players)
+	}
+}
+
+func TestProjectile_WaterPierces(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	field := g.GetMap("field")
+	mons := lineOfMonsters(field, 4)
+
+	field.AddProjectile(&game.Projectile{ID: 1, X: 150, Y: 500, VX: 1, Type: game.ProjectileTypeWater})
+	flyProjectile(g, field, 30)
+
+	hit := 0
+	for _, mon := range mons {
+		if mon.HP < mon.MaxHP {
+			hit++
+		}
+	}
+	if hit != 3 {
+		t.Errorf("Expected water projectile to hit 3 monsters, hit %d", hit)
+	}
+	if len(field.Projectiles) != 0 {
+		t.Error("Expected projectile to be removed after using its pierce")
+	}
+}
+
+func TestProjectile_DefaultStopsAtFirstHit(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	field := g.GetMap("field")
+	mons := lineOfMonsters(field, 2)
+
+	field.AddProjectile(&game.Projectile{ID: 1, X: 150, Y: 500, VX: 1, Type: game.ProjectileTypeDefault})
+	flyProjectile(g, field, 30)
+
+	if mons[0].HP == mons[0].MaxHP || mons[1].HP != mons[1].MaxHP {
+		t.Error("Expected default projectile to hit only the first monster")
+	}
+}
+
+func TestProjectile_ExpiresAtRange(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	field := g.GetMap("field")
+	lineOfMonsters(field, 0)
+
+	field.AddProjectile(&game.Projectile{ID: 1, X: 0, Y: 500, VX: 1, Type: game.ProjectileTypeDefault})
+	flyProjectile(g, field, 49)
+	if len(field.Projectiles) != 1 {
+		t.Fatal("Expected projectile to still be in flight")
+	}
+	flyProjectile(g, field, 1)
+	if len(field.Projectiles) != 0 {
+		t.Error("Expected projectile to expire after 500 units")
+	}
+}
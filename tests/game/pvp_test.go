@@ -0,0 +1,84 @@
+package game_test
+
+import (
+	"mmorpg/internal/game"
+	"testing"
+)
+
+func TestPvP_DuelLoserSurvives(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	a := g.AddPlayer(nil)
+	b := g.AddPlayer(nil)
+
+	g.RequestDuel(a, b.ID)
+	if b.Duel == nil {
+		t.Fatal("Expected duel request to reach b")
+	}
+	g.AcceptDuel(b, b.Duel.ID)
+
+	// Nova is an AoE centered on the caster and hits duel opponents
+	b.HP = 1
+	g.Cast(a, "nova", 0, 0, 0)
+	g.Update()
+
+	if b.HP != 1 {
+		t.Errorf("Expected duel loser left at 1 HP, got %d", b.HP)
+	}
+	if a.Duel != nil || b.Duel != nil {
+		t.Error("Expected duel to end after defeat")
+	}
+	if b.MapID != "town" || a.PvPKills != 0 {
+		t.Error("Expected no PvP kill or respawn for a duel")
+	}
+}
+
+func TestPvP_NoDamageOutsidePvPMaps(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	a := g.AddPlayer(nil)
+	b := g.AddPlayer(nil)
+
+	g.Cast(a, "nova", 0, 0, 0)
+	if b.HP != b.MaxHP {
+		t.Errorf("Expected no damage in town, got HP %d", b.HP)
+	}
+}
+
+func TestPvP_KillDropsGold(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	a := g.AddPlayer(nil)
+	b := g.AddPlayer(nil)
+	a.MapID, b.MapID = "dungeon", "dungeon"
+	b.Gold = 1000
+	b.HP = 1
+
+	g.Cast(a, "nova", 0, 0, 0)
+	g.Update()
+
+	if a.PvPKills != 1 || b.PvPDeaths != 1 {
+		t.Errorf("Expected kill credit, got kills=%d deaths=%d", a.PvPKills, b.PvPDeaths)
+	}
+	if b.Gold != 900 {
+		t.Errorf("Expected 900 gold after penalty, got %d", b.Gold)
+	}
+	if b.MapID != "town" || b.HP != b.MaxHP {
+		t.Error("Expected victim to respawn in town at full HP")
+	}
+}
+
+func TestPvP_PartyMembersCantHurtEachOther(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	a := g.AddPlayer(nil)
+	b := g.AddPlayer(nil)
+	a.MapID, b.MapID = "dungeon", "dungeon"
+
+	g.InviteToParty(a, b.ID)
+	g.AcceptParty(b, a.Party.ID)
+	if b.Party == nil || b.Party != a.Party {
+		t.Fatal("Expected a and b to share a party")
+	}
+
+	g.Cast(a, "nova", 0, 0, 0)
+	if b.HP != b.MaxHP {
+		t.Errorf("Expected no friendly fire, got HP %d", b.HP)
+	}
+}
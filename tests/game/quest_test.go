@@ -0,0 +1,157 @@
+package game_test
+
+import (
+	"mmorpg/internal/game"
+	"testing"
+)
+
+func TestQuest_Delivery(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	p := g.AddPlayer(nil)
+	p.Move(350, 220)
+
+	// Town Guide (NPC 3): "Any work for me?" -> "I'll help the Shopkeeper."
+	g.Interact(p, 3)
+	g.ChooseDialogOption(p, 3, 2)
+	g.ChooseDialogOption(p, 3, 2)
+
+	if _, ok := p.Quests["sword_delivery"]; !ok {
+		t.Fatal("Expected sword_delivery to be active")
+	}
+
+	invBefore := len(p.Inventory)
+	g.Interact(p, 1)
+
+	if _, ok := p.Quests["sword_delivery"]; ok {
+		t.Error("Expected sword_delivery to be completed")
+	}
+	if !p.CompletedQuests["sword_delivery"] {
+		t.Error("Expected sword_delivery in completed quests")
+	}
+	if len(p.Inventory) != invBefore-1 {
+		t.Errorf("Expected one item delivered, inventory %d -> %d", invBefore, len(p.Inventory))
+	}
+	if p.Gold != 150 || p.XP != 20 {
+		t.Errorf("Expected 150 gold and 20 XP, got %d and %d", p.Gold, p.XP)
+	}
+
+	// Non-repeatable quests can't be taken again
+	g.Interact(p, 3)
+	g.ChooseDialogOption(p, 3, 2)
+	g.ChooseDialogOption(p, 3, 2)
+	if _, ok := p.Quests["sword_delivery"]; ok {
+		t.Error("Expected completed quest not to be accepted again")
+	}
+}
+
+func TestQuest_DeliverySkipsTradeOffers(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	p := g.AddPlayer(nil)
+	other := g.AddPlayer(nil)
+	p.Move(350, 220)
+	other.Move(350, 240)
+
+	g.Interact(p, 3)
+	g.ChooseDialogOption(p, 3, 2)
+	g.ChooseDialogOption(p, 3, 2)
+
+	g.RequestTrade(p, other.ID)
+	g.AcceptTrade(other, p.Trade.ID)
+	offered := p.Inventory[0]
+	g.TradeAddItem(p, offered.ID)
+
+	g.Interact(p, 1)
+
+	if !p.CompletedQuests["sword_delivery"] {
+		t.Fatal("Expected sword_delivery to be completed with another sword")
+	}
+	found := false
+	for _, it := range p.Inventory {
+		if it == offered {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Expected the sword offered in the trade not to be delivered")
+	}
+}
+
+func TestQuest_Kill(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	p := g.AddPlayer(nil)
+	p.Move(350, 220)
+
+	// Town Guide: "Any work for me?" -> "I'll hunt water monsters."
+	g.Interact(p, 3)
+	g.ChooseDialogOption(p, 3, 2)
+	g.ChooseDialogOption(p, 3, 0)
+	if _, ok := p.Quests["water_hunt"]; !ok {
+		t.Fatal("Expected water_hunt to be active")
+	}
+
+	field := g.GetMap("field")
+	mons := lineOfMonsters(field, 6)
+	for i, mon := range mons {
+		mon.Type = game.MonsterTypeWater
+		if i == 0 {
+			mon.Type = game.MonsterTypeFire
+		}
+		mon.HP = 1
+	}
+
+	for i, mon := range mons {
+		field.AddProjectile(&game.Projectile{ID: i + 1, OwnerID: p.ID, X: mon.X - 50, Y: mon.Y, VX: 1, Type: game.ProjectileTypeDefault})
+		flyProjectile(g, field, 30)
+		if i < len(mons)-1 {
+			if state, ok := p.Quests["water_hunt"]; !ok || state.Progress[0] != i {
+				t.Fatalf("Expected %d water kills counted after %d kills", i, i+1)
+			}
+		}
+	}
+
+	if !p.CompletedQuests["water_hunt"] {
+		t.Fatal("Expected water_hunt to be completed after 5 water kills")
+	}
+	if p.Gold != 200 || p.XP != 50 {
+		t.Errorf("Expected 200 gold and 50 XP, got %d and %d", p.Gold, p.XP)
+	}
+}
+
+func TestQuest_Collect(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	p := g.AddPlayer(nil)
+	p.Move(350, 220)
+
+	// Town Guide: "Any work for me?" -> "I'll gather some gold."
+	g.Interact(p, 3)
+	g.ChooseDialogOption(p, 3, 2)
+	g.ChooseDialogOption(p, 3, 1)
+	if _, ok := p.Quests["gold_rush"]; !ok {
+		t.Fatal("Expected gold_rush to be active")
+	}
+	p.Inventory = p.Inventory[:10]
+
+	town := g.GetMap("town")
+	for i := 1; i <= 3; i++ {
+		town.Items[i] = &game.Item{ID: i, Type: game.ItemTypeGold, X: p.X, Y: p.Y}
+		town.CheckCollisions([]*game.Player{p})
+	}
+
+	if !p.CompletedQuests["gold_rush"] {
+		t.Fatal("Expected gold_rush to be completed after 3 gold pickups")
+	}
+	if p.XP != 30 {
+		t.Errorf("Expected 30 XP, got %d", p.XP)
+	}
+	if last := p.Inventory[len(p.Inventory)-1]; last.Name != "Miner's Helmet" {
+		t.Errorf("Expected the Miner's Helmet reward, got %q", last.Name)
+	}
+
+	// Repeatable quests can be taken again
+	g.Interact(p, 3)
+	g.ChooseDialogOption(p, 3, 2)
+	g.ChooseDialogOption(p, 3, 1)
+	if _, ok := p.Quests["gold_rush"]; !ok {
+		t.Error("Expected repeatable gold_rush to be accepted again")
+	}
+}
@@ -0,0 +1,168 @@
+package game_test
+
+import (
+	"encoding/json"
+	"mmorpg/internal/game"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recConn records messages and remembers the last WELCOME token
+type recConn struct {
+	mu     sync.Mutex
+	types  []string
+	token  string
+	closed bool
+}
+
+func (c *recConn) Write(b []byte) (int, error) {
+	var msg struct {
+		Type  string `json:"type"`
+		Token string `json:"token"`
+	}
+	json.Unmarshal(b, &msg)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.types = append(c.types, msg.Type)
+	if msg.Type == "WELCOME" {
+		c.token = msg.Token
+	}
+	return len(b), nil
+}
+
+func (c *recConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	return nil
+}
+
+func (c *recConn) sent(typ string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, t := range c.types {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
+
+func TestReconnect_Resume(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	oldConn := &recConn{}
+	p := g.AddPlayer(oldConn)
+	if oldConn.token == "" {
+		t.Fatal("Expected WELCOME to carry a resume token")
+	}
+	p.Gold = 500
+
+	g.DisconnectPlayer(p.ID, oldConn, true)
+	g.Update()
+	if g.GetPlayers()[p.ID] == nil {
+		t.Fatal("Expected the player to be held during the grace period")
+	}
+
+	newConn := &recConn{}
+	fresh := g.AddPlayer(newConn)
+	resumed, ok := g.ResumePlayer(fresh, oldConn.token)
+	if !ok || resumed != p {
+		t.Fatal("Expected RESUME to reattach the held player")
+	}
+	if p.Conn != newConn || p.Disconnected() {
+		t.Error("Expected the player to use the new connection")
+	}
+	if g.GetPlayers()[fresh.ID] != nil {
+		t.Error("Expected the placeholder player to be removed")
+	}
+	if newConn.token != oldConn.token || !newConn.sent("MARKET_UPDATE") {
+		t.Error("Expected full state to be resent for the resumed player")
+	}
+
+	// The old session ending late must not touch the resumed player
+	g.DisconnectPlayer(p.ID, oldConn, true)
+	if p.Disconnected() {
+		t.Error("Expected a stale connection to be ignored")
+	}
+}
+
+func TestReconnect_BadToken(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	conn := &recConn{}
+	fresh := g.AddPlayer(conn)
+
+	p, ok := g.ResumePlayer(fresh, "nope")
+	if ok || p != fresh {
+		t.Error("Expected an unknown token to keep the new player")
+	}
+	if !conn.sent("RESUME_FAILED") {
+		t.Error("Expected RESUME_FAILED")
+	}
+}
+
+func TestReconnect_TakesOverOpenConnection(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	oldConn := &recConn{}
+	p := g.AddPlayer(oldConn)
+
+	fresh := g.AddPlayer(&recConn{})
+	if _, ok := g.ResumePlayer(fresh, oldConn.token); !ok {
+		t.Fatal("Expected resume to succeed while the old connection is open")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	oldConn.mu.Lock()
+	defer oldConn.mu.Unlock()
+	if !oldConn.closed {
+		t.Error("Expected the old connection to be closed")
+	}
+	if p.Disconnected() {
+		t.Error("Expected the player to be connected")
+	}
+}
+
+func TestReconnect_GraceExpires(t *testing.T) {
+	cfg := game.DefaultConfig()
+	cfg.ReconnectGrace = 20 * time.Millisecond
+	g := game.NewGame(cfg)
+	conn := &recConn{}
+	p := g.AddPlayer(conn)
+
+	g.DisconnectPlayer(p.ID, conn, true)
+	time.Sleep(30 * time.Millisecond)
+	g.Update()
+	if g.GetPlayers()[p.ID] != nil {
+		t.Error("Expected the player to be removed after the grace period")
+	}
+}
+
+func TestReconnect_NoHold(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	conn := &recConn{}
+	p := g.AddPlayer(conn)
+
+	g.DisconnectPlayer(p.ID, conn, false)
+	if g.GetPlayers()[p.ID] != nil {
+		t.Error("Expected the player to be removed at once")
+	}
+}
+
+func TestReconnect_HeldPlayerIsInvulnerable(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	conn := &recConn{}
+	p := g.AddPlayer(conn)
+	p.MapID = "field"
+	p.X, p.Y = 200, 500
+
+	field := g.GetMap("field")
+	mons := lineOfMonsters(field, 1)
+	mons[0].X, mons[0].Y = 205, 500
+
+	g.DisconnectPlayer(p.ID, conn, true)
+	g.Update()
+	if p.HP != p.MaxHP {
+		t.Errorf("Expected a held player to take no damage, HP %d/%d", p.HP, p.MaxHP)
+	}
+}
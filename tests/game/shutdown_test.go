@@ -0,0 +1,213 @@
+package game_test
+
+import (
+	"encoding/json"
+	"mmorpg/internal/game"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSaveState_RoundTripsMarket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	g := game.NewGame(game.DefaultConfig())
+	p := g.AddPlayer(nil)
+	p.Gold = 250
+	g.ListMarketItem(p, p.Inventory[0].ID, 40)
+
+	if err := g.SaveState(path); err != nil {
+		t.Fatalf("SaveState: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var state game.SavedState
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Players) != 1 || state.Players[0].Gold != 250 {
+		t.Errorf("Expected saved player with 250 gold, got %+v", state.Players)
+	}
+
+	g2 := game.NewGame(game.DefaultConfig())
+	if err := g2.LoadState(path); err != nil {
+		t.Fatalf("LoadState: %v", err)
+	}
+	newcomer := g2.AddPlayer(nil)
+	if newcomer.ID == p.ID {
+		t.Fatalf("Expected player IDs to continue after the saved ones, got %d again", newcomer.ID)
+	}
+	g2.BuyMarketItem(newcomer, state.Market[0].ID)
+	if len(g2.GetPlayers()[newcomer.ID].Inventory) != 20 {
+		t.Error("Expected a new player not to take back someone else's listing for free")
+	}
+
+	buyer := g2.AddPlayer(nil)
+	buyer.Gold = 100
+	g2.BuyMarketItem(buyer, state.Market[0].ID)
+	if buyer.Gold != 60 {
+		t.Errorf("Expected restored listing to be buyable, buyer has %d gold", buyer.Gold)
+	}
+	if newcomer.Gold != 0 {
+		t.Errorf("Expected the sale not to pay a new player, got %d gold", newcomer.Gold)
+	}
+	if seller := g2.GetPlayers()[p.ID]; seller == nil || seller.Gold != 290 {
+		t.Error("Expected the sale to pay the restored seller")
+	}
+}
+
+func TestLoadState_RestoresHeldPlayers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	g := game.NewGame(game.DefaultConfig())
+	conn := &recConn{}
+	p := g.AddPlayer(conn)
+	p.Gold = 70
+	p.Move(500, 400)
+	p.Equip(p.Inventory[0].ID, 0)
+	if err := g.SaveState(path); err != nil {
+		t.Fatalf("SaveState: %v", err)
+	}
+
+	g2 := game.NewGame(game.DefaultConfig())
+	if err := g2.LoadState(path); err != nil {
+		t.Fatalf("LoadState: %v", err)
+	}
+	held := g2.GetPlayers()[p.ID]
+	if held == nil || !held.Disconnected() {
+		t.Fatal("Expected the saved player to be held for reconnect")
+	}
+
+	fresh := g2.AddPlayer(&recConn{})
+	resumed, ok := g2.ResumePlayer(fresh, conn.token)
+	if !ok || resumed != held {
+		t.Fatal("Expected the saved token to resume the restored player")
+	}
+	if resumed.Gold != 70 || resumed.X != 500 || resumed.Y != 400 || resumed.MapID != p.MapID {
+		t.Errorf("Expected gold and position restored, got %d at %s (%.0f, %.0f)", resumed.Gold, resumed.MapID, resumed.X, resumed.Y)
+	}
+	if resumed.Equipment[0] == nil || resumed.Attack != p.Attack {
+		t.Errorf("Expected equipment and its stats restored, attack %d want %d", resumed.Attack, p.Attack)
+	}
+
+	// Without a grace period nobody could resume them
+	cfg := game.DefaultConfig()
+	cfg.ReconnectGrace = 0
+	g3 := game.NewGame(cfg)
+	g3.LoadState(path)
+	if len(g3.GetPlayers()) != 0 {
+		t.Error("Expected no players restored without a reconnect grace")
+	}
+}
+
+func TestLoadState_RestoresQuestProgress(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	g := game.NewGame(game.DefaultConfig())
+	conn := &recConn{}
+	p := g.AddPlayer(conn)
+	p.Move(350, 220)
+	g.Interact(p, 3)
+	g.ChooseDialogOption(p, 3, 2)
+	g.ChooseDialogOption(p, 3, 0)
+	if _, ok := p.Quests["water_hunt"]; !ok {
+		t.Fatal("Expected water_hunt to be active")
+	}
+	p.Quests["water_hunt"].Progress[0] = 3
+	p.Mana = 40
+	until := time.Now().Add(time.Minute)
+	p.Cooldowns["potion"] = until
+	if err := g.SaveState(path); err != nil {
+		t.Fatalf("SaveState: %v", err)
+	}
+
+	g2 := game.NewGame(game.DefaultConfig())
+	if err := g2.LoadState(path); err != nil {
+		t.Fatalf("LoadState: %v", err)
+	}
+	held := g2.GetPlayers()[p.ID]
+	if held == nil {
+		t.Fatal("Expected the saved player to be restored")
+	}
+	qs, ok := held.Quests["water_hunt"]
+	if !ok {
+		t.Fatal("Expected the quest in progress to survive a restart")
+	}
+	if qs.Quest == nil || qs.Quest.ID != "water_hunt" || qs.Progress[0] != 3 {
+		t.Errorf("Expected water_hunt at 3 kills, got %+v", qs)
+	}
+	if held.Mana != 40 {
+		t.Errorf("Expected 40 mana restored, got %d", held.Mana)
+	}
+	if !held.Cooldowns["potion"].Equal(until) {
+		t.Errorf("Expected potion cooldown until %v, got %v", until, held.Cooldowns["potion"])
+	}
+}
+
+func TestLoadState_MissingFile(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	if err := g.LoadState(filepath.Join(t.TempDir(), "nope.json")); err != nil {
+		t.Errorf("Expected missing state file to be ignored, got %v", err)
+	}
+}
+
+func TestStop_EndsGameLoop(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	done := make(chan struct{})
+	go func() {
+		g.Start()
+		close(done)
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	g.Stop()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected Start to return after Stop")
+	}
+	g.Stop()
+
+	if g.TickStats().Ticks == 0 {
+		t.Error("Expected tick metrics to be recorded while running")
+	}
+}
+
+func TestStop_BeforeStart(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	g.Stop()
+
+	done := make(chan struct{})
+	go func() {
+		g.Start()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected Start to return at once after Stop")
+	}
+}
+
+func TestStop_RacingStart(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		g := game.NewGame(game.DefaultConfig())
+		done := make(chan struct{})
+		go func() {
+			g.Start()
+			close(done)
+		}()
+		g.Stop()
+
+		// Stop must not return while the loop still runs
+		select {
+		case <-done:
+		case <-time.After(100 * time.Millisecond):
+			t.Fatal("Expected Start to have returned once Stop returned")
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package game_test
+
+import (
+	"mmorpg/internal/game"
+	"testing"
+)
+
+func TestSkill_NovaHitsNearbyMonsters(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	p := g.AddPlayer(nil)
+	field := g.GetMap("field")
+	p.MapID = "field"
+
+	field.SpawnMonster()
+	var mon *game.Monster
+	for _, m := range field.Monsters {
+		mon = m
+	}
+	mon.MaxHP, mon.HP = 1000, 1000
+	p.Move(mon.X+50, mon.Y)
+
+	g.Cast(p, "nova", 0, 0, 0)
+
+	if mon.HP >= 1000 {
+		t.Error("Expected nova to damage the monster")
+	}
+	if p.Mana != 70 {
+		t.Errorf("Expected 70 mana after nova, got %d", p.Mana)
+	}
+
+	g.Cast(p, "nova", 0, 0, 0)
+	if p.Mana != 70 {
+		t.Error("Expected second nova to be blocked by cooldown")
+	}
+}
+
+func TestSkill_InvalidTargetCostsNothing(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	p := g.AddPlayer(nil)
+
+	g.Cast(p, "fireball", 12345, 0, 0)
+	if p.Mana != p.MaxMana {
+		t.Errorf("Expected no mana spent, got %d/%d", p.Mana, p.MaxMana)
+	}
+}
@@ -0,0 +1,46 @@
+package game_test
+
+import (
+	"mmorpg/internal/game"
+	"testing"
+	"time"
+)
+
+func TestStatusEffects_BurnStacksAndTicks(t *testing.T) {
+	var effects game.StatusEffects
+	now := time.Now()
+
+	effects.Apply(game.StatusBurn, 1, now)
+	effects.Apply(game.StatusBurn, 1, now)
+	if len(effects) != 1 || effects[0].Stacks != 2 {
+		t.Fatalf("Expected a single burn with 2 stacks, got %d effects", len(effects))
+	}
+
+	damage, source, _ := effects.Tick(now.Add(time.Second))
+	// Two ticks (0.5s, 1s) of 3 damage per stack
+	if damage != 12 {
+		t.Errorf("Expected 12 burn damage, got %d", damage)
+	}
+	if source != 1 {
+		t.Errorf("Expected damage credited to 1, got %d", source)
+	}
+
+	_, _, changed := effects.Tick(now.Add(5 * time.Second))
+	if !changed || len(effects) != 0 {
+		t.Error("Expected burn to expire")
+	}
+}
+
+func TestStatusEffects_RootStopsMovement(t *testing.T) {
+	p := game.NewPlayer(1, nil, nil)
+	p.ApplyStatus(game.StatusRoot, 0)
+
+	if p.Speed != 0 {
+		t.Errorf("Expected speed 0 while rooted, got %.2f", p.Speed)
+	}
+
+	p.Move(10, 10)
+	if p.X != 400 || p.Y != 300 {
+		t.Errorf("Expected rooted player to stay put, got (%.2f,%.2f)", p.X, p.Y)
+	}
+}
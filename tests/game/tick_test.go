@@ -0,0 +1,68 @@
+package game_test
+
+import (
+	"math"
+	"mmorpg/internal/game"
+	"testing"
+	"time"
+)
+
+func TestSetTickRate(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	if g.TickInterval() != time.Second/30 {
+		t.Errorf("Expected default 30Hz tick, got %v", g.TickInterval())
+	}
+
+	g.SetTickRate(20)
+	if g.TickInterval() != 50*time.Millisecond {
+		t.Errorf("Expected 50ms tick at 20Hz, got %v", g.TickInterval())
+	}
+}
+
+func TestProjectile_SpeedIndependentOfTickRate(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	town := g.GetMap("town")
+	town.SetWalls(nil)
+
+	town.AddProjectile(&game.Projectile{ID: 1, X: 0, Y: 300, VX: 1, Type: game.ProjectileTypeDefault})
+	for i := 0; i < 30; i++ {
+		town.UpdateProjectiles(time.Now(), 1.0/30)
+	}
+	if x := town.Projectiles[1].X; math.Abs(x-300) > 0.001 {
+		t.Errorf("Expected 300 units in one second at 30Hz, got %.2f", x)
+	}
+
+	town.AddProjectile(&game.Projectile{ID: 2, X: 0, Y: 400, VX: 1, Type: game.ProjectileTypeDefault})
+	for i := 0; i < 10; i++ {
+		town.UpdateProjectiles(time.Now(), 1.0/10)
+	}
+	if x := town.Projectiles[2].X; math.Abs(x-300) > 0.001 {
+		t.Errorf("Expected 300 units in one second at 10Hz, got %.2f", x)
+	}
+}
+
+func TestItemExpiry_FollowsGameClock(t *testing.T) {
+	cfg := game.DefaultConfig()
+	cfg.ItemExpiry = time.Second
+	g := game.NewGame(cfg)
+	g.AddPlayer(nil)
+	town := g.GetMap("town")
+
+	g.Update()
+	town.DropGold(50, 50, 10, nil)
+
+	// Ticks run back to back here, far faster than real time, so only a
+	// clock that advances per tick can expire the item
+	for i := 0; i < 25; i++ {
+		g.Update()
+	}
+	if len(town.Items) != 1 {
+		t.Fatalf("Expected gold to stay on the ground for under a second of game time, got %d items", len(town.Items))
+	}
+	for i := 0; i < 10; i++ {
+		g.Update()
+	}
+	if len(town.Items) != 0 {
+		t.Errorf("Expected gold to expire after a second of game time, got %d items", len(town.Items))
+	}
+}
@@ -0,0 +1,118 @@
+package game_test
+
+import (
+	"mmorpg/internal/game"
+	"testing"
+)
+
+func TestTrade_Swap(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	a := g.AddPlayer(nil)
+	b := g.AddPlayer(nil)
+	a.Gold = 500
+
+	g.RequestTrade(a, b.ID)
+	if a.Trade == nil || a.Trade != b.Trade {
+		t.Fatal("Expected both players to share a trade session")
+	}
+	g.AcceptTrade(b, a.Trade.ID)
+
+	sword := a.Inventory[0]
+	// Make room on b's side so the swap fits
+	b.Inventory = b.Inventory[:10]
+
+	g.TradeAddItem(a, sword.ID)
+	g.TradeSetGold(a, 200)
+	g.ConfirmTrade(a)
+	g.ConfirmTrade(b)
+
+	if a.Trade != nil || b.Trade != nil {
+		t.Fatal("Expected trade to be closed after both confirmations")
+	}
+	if a.Gold != 300 || b.Gold != 200 {
+		t.Errorf("Expected gold 300/200, got %d/%d", a.Gold, b.Gold)
+	}
+	if len(a.Inventory) != 19 || len(b.Inventory) != 11 {
+		t.Errorf("Expected inventories 19/11, got %d/%d", len(a.Inventory), len(b.Inventory))
+	}
+	if b.Inventory[len(b.Inventory)-1] != sword {
+		t.Error("Expected traded sword at the end of b's inventory")
+	}
+}
+
+func TestTrade_ChangeResetsConfirmation(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	a := g.AddPlayer(nil)
+	b := g.AddPlayer(nil)
+	a.Gold = 100
+
+	g.RequestTrade(a, b.ID)
+	g.AcceptTrade(b, a.Trade.ID)
+
+	g.ConfirmTrade(a)
+	g.TradeSetGold(a, 50)
+	g.ConfirmTrade(b)
+
+	if a.Trade == nil {
+		t.Fatal("Expected trade to stay open after an offer change")
+	}
+	if a.Gold != 100 || b.Gold != 0 {
+		t.Errorf("Expected no gold movement, got %d/%d", a.Gold, b.Gold)
+	}
+}
+
+func TestTrade_CancelOnDisconnect(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	a := g.AddPlayer(nil)
+	b := g.AddPlayer(nil)
+
+	g.RequestTrade(a, b.ID)
+	g.RemovePlayer(b.ID)
+
+	if a.Trade != nil {
+		t.Error("Expected trade to be cancelled when partner disconnects")
+	}
+}
+
+func TestTrade_OfferedStackIsFrozen(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	a := g.AddPlayer(nil)
+	b := g.AddPlayer(nil)
+	a.Inventory = a.Inventory[:10]
+	b.Inventory = b.Inventory[:10]
+
+	potions := game.NewConsumable("potion_small", 5)
+	potions.ID = 1
+	a.Inventory = append(a.Inventory, potions)
+	a.SetHotbar(0, potions.ID)
+	a.HP = 10
+
+	g.RequestTrade(a, b.ID)
+	g.AcceptTrade(b, a.Trade.ID)
+	g.TradeAddItem(a, potions.ID)
+	g.ConfirmTrade(b)
+
+	// Drinking from or adding to the offered stack would change the deal
+	a.UseItem(potions.ID)
+	a.UseHotbar(0)
+	town := g.GetMap("town")
+	more := game.NewConsumable("potion_small", 3)
+	more.ID, more.X, more.Y = 2, a.X, a.Y
+	town.Items[more.ID] = more
+	town.CheckCollisions([]*game.Player{a})
+
+	if potions.Count != 5 || a.HP != 10 {
+		t.Fatalf("Expected the offered stack to stay at 5 unused, got %d (HP %d)", potions.Count, a.HP)
+	}
+
+	g.ConfirmTrade(a)
+	if a.Trade != nil {
+		t.Fatal("Expected the trade to complete")
+	}
+	if b.Inventory[len(b.Inventory)-1] != potions || potions.Count != 5 {
+		t.Errorf("Expected b to get the 5 potions it confirmed, got %d", potions.Count)
+	}
+	if a.Inventory[len(a.Inventory)-1].Count != 3 {
+		t.Error("Expected the picked up potions in a stack of their own")
+	}
+}
@@ -0,0 +1,184 @@
+package network_test
+
+import (
+	"encoding/json"
+	"mmorpg/internal/game"
+	"mmorpg/internal/network"
+	"strings"
+	"testing"
+	"time"
+)
+
+// helloCodec is a memCodec whose client sends first instead of the usual HELLO
+func helloCodec(first string) *memCodec {
+	c := &memCodec{in: make(chan []byte, 8), closed: make(chan struct{})}
+	c.in <- []byte(first)
+	return c
+}
+
+// sent decodes the messages of the given type written to c
+func (c *memCodec) sent(typ string) []map[string]interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var msgs []map[string]interface{}
+	for _, b := range c.out {
+		var msg map[string]interface{}
+		if json.Unmarshal(b, &msg) == nil && msg["type"] == typ {
+			msgs = append(msgs, msg)
+		}
+	}
+	return msgs
+}
+
+func TestHello_Rejects(t *testing.T) {
+	for name, first := range map[string]string{
+		"too new": `{"type":"HELLO","version":99}`,
+		"too old": `{"type":"HELLO","version":0}`,
+		"codec":   `{"type":"HELLO","version":2,"codecs":["msgpack"]}`,
+	} {
+		g := game.NewGame(game.DefaultConfig())
+		hub := network.NewHub(g, network.DefaultSessionConfig())
+
+		c := helloCodec(first)
+		waitDone(t, serve(hub, c))
+
+		if c.closeReason() != network.CloseIncompatible {
+			t.Errorf("%s: expected close reason %q, got %q", name, network.CloseIncompatible, c.closeReason())
+		}
+		rejected := c.sent("HELLO_REJECTED")
+		if len(rejected) != 1 || rejected[0]["reason"] == "" {
+			t.Errorf("%s: expected a HELLO_REJECTED with a reason, got %v", name, rejected)
+		}
+		if len(g.GetPlayers()) != 0 {
+			t.Errorf("%s: expected no player to join", name)
+		}
+	}
+}
+
+func TestHello_Negotiates(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	hub := network.NewHub(g, network.DefaultSessionConfig())
+
+	c := helloCodec(`{"type":"HELLO","version":2,"codecs":["msgpack","json"],"capabilities":["resume","delta_snapshots","compression"]}`)
+	done := serve(hub, c)
+	time.Sleep(20 * time.Millisecond)
+
+	oks := c.sent("HELLO_OK")
+	if len(oks) != 1 {
+		t.Fatalf("Expected HELLO_OK, got %v", oks)
+	}
+	ok := oks[0]
+	if ok["codec"] != "json" || ok["version"] != float64(2) {
+		t.Errorf("Expected json at version 2, got %v", ok)
+	}
+	if caps, _ := ok["capabilities"].([]interface{}); len(caps) != 1 || caps[0] != "resume" {
+		t.Errorf("Expected only resume to be enabled, got %v", ok["capabilities"])
+	}
+
+	c.mu.Lock()
+	first := string(c.out[0])
+	c.mu.Unlock()
+	if !strings.Contains(first, "HELLO_OK") {
+		t.Errorf("Expected HELLO_OK before the game state, got %s", first)
+	}
+
+	hub.Shutdown()
+	waitDone(t, done)
+}
+
+func TestHello_OlderVersionGetsNPCsInSnap(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	hub := network.NewHub(g, network.DefaultSessionConfig())
+
+	v1 := helloCodec(`{"type":"HELLO","version":1}`)
+	v2 := helloCodec(`{"type":"HELLO","version":2}`)
+	done1, done2 := serve(hub, v1), serve(hub, v2)
+	time.Sleep(20 * time.Millisecond)
+	g.Update()
+	time.Sleep(20 * time.Millisecond)
+
+	for _, tc := range []struct {
+		c        *memCodec
+		snapNPCs bool
+	}{{v1, true}, {v2, false}} {
+		snaps := tc.c.sent("SNAP")
+		if len(snaps) == 0 {
+			t.Fatal("Expected a SNAP")
+		}
+		if _, ok := snaps[0]["npcs"]; ok != tc.snapNPCs {
+			t.Errorf("Expected NPCs in SNAP to be %v, got %v", tc.snapNPCs, ok)
+		}
+		switches := tc.c.sent("MAP_SWITCH")
+		if len(switches) == 0 || switches[0]["npcs"] == nil {
+			t.Error("Expected NPCs in MAP_SWITCH")
+		}
+	}
+
+	hub.Shutdown()
+	waitDone(t, done1)
+	waitDone(t, done2)
+}
+
+func TestHello_LegacyClientWithoutHello(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	hub := network.NewHub(g, network.DefaultSessionConfig())
+
+	c := helloCodec(`{"type":"MOVE","x":410,"y":300}`)
+	done := serve(hub, c)
+	time.Sleep(20 * time.Millisecond)
+	g.Update()
+	time.Sleep(20 * time.Millisecond)
+
+	if c.closeReason() != "" || len(c.sent("HELLO_REJECTED")) != 0 {
+		t.Fatalf("Expected a client without HELLO to be served, closed with %q", c.closeReason())
+	}
+	if len(c.sent("HELLO_OK")) != 0 || len(c.sent("WELCOME")) != 1 {
+		t.Error("Expected the player to join without a HELLO_OK")
+	}
+	snaps := c.sent("SNAP")
+	if len(snaps) == 0 || snaps[0]["npcs"] == nil {
+		t.Error("Expected NPCs in SNAP for a legacy client")
+	}
+	for _, p := range g.GetPlayers() {
+		if p.X != 410 {
+			t.Errorf("Expected the first message to be handled, player at x %.0f", p.X)
+		}
+	}
+
+	hub.Shutdown()
+	waitDone(t, done)
+}
+
+func TestHello_SilentLegacyClient(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	hub := network.NewHub(g, network.DefaultSessionConfig())
+
+	// Some clients from before HELLO wait for the server to speak first
+	c := &memCodec{in: make(chan []byte, 8), closed: make(chan struct{})}
+	done := serve(hub, c)
+	deadline := time.Now().Add(5 * time.Second)
+	for len(c.sent("WELCOME")) == 0 && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	if c.closeReason() != "" {
+		t.Fatalf("Expected a silent client to be served, closed with %q", c.closeReason())
+	}
+	if len(c.sent("WELCOME")) != 1 || len(c.sent("HELLO_OK")) != 0 {
+		t.Fatal("Expected the player to join as version 1 without a HELLO_OK")
+	}
+
+	// The read started while waiting for HELLO still delivers
+	c.in <- []byte(`{"type":"MOVE","x":410,"y":300}`)
+	time.Sleep(20 * time.Millisecond)
+	g.Update()
+	for _, p := range g.GetPlayers() {
+		if p.X != 410 {
+			t.Errorf("Expected the first message to be handled, player at x %.0f", p.X)
+		}
+	}
+
+	hub.Shutdown()
+	waitDone(t, done)
+}
@@ -0,0 +1,106 @@
+package network_test
+
+import (
+	"bytes"
+	"errors"
+	"mmorpg/internal/game"
+	"mmorpg/internal/network"
+	"os"
+	"strings"
+	"testing"
+)
+
+type msgPing struct {
+	Type  string `json:"type"`
+	Count int    `json:"count"`
+}
+
+func (m *msgPing) Validate() error {
+	if m.Count < 0 {
+		return errors.New("negative count")
+	}
+	return nil
+}
+
+func TestRegistry_DecodeValidateAndMiddleware(t *testing.T) {
+	r := network.NewRegistry()
+	var order []string
+	trace := func(name string) network.Middleware {
+		return func(next network.HandlerFunc) network.HandlerFunc {
+			return func(c *network.Context) error {
+				order = append(order, name)
+				return next(c)
+			}
+		}
+	}
+	r.Use(trace("global"))
+
+	var got int
+	network.Register(r, "PING", "Test message.", func(c *network.Context, m *msgPing) {
+		got = m.Count
+	}, trace("first"), trace("second"))
+
+	p := game.NewPlayer(1, nil, nil)
+	if err := r.Dispatch(&network.Context{Player: p}, []byte(`{"type":"PING","count":3}`)); err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+	if got != 3 {
+		t.Errorf("Expected decoded count 3, got %d", got)
+	}
+	if strings.Join(order, ",") != "global,first,second" {
+		t.Errorf("Expected middleware in declaration order, got %v", order)
+	}
+
+	got = 0
+	if err := r.Dispatch(&network.Context{Player: p}, []byte(`{"type":"PING","count":-1}`)); err == nil || got != 0 {
+		t.Error("Expected a message failing validation not to be handled")
+	}
+	if err := r.Dispatch(&network.Context{Player: p}, []byte(`{"type":"PING","count":"x"}`)); err == nil {
+		t.Error("Expected a decode error")
+	}
+	if err := r.Dispatch(&network.Context{Player: p}, []byte(`{"type":"NOPE"}`)); err != network.ErrUnknownMessage {
+		t.Errorf("Expected ErrUnknownMessage, got %v", err)
+	}
+}
+
+func TestHandlers_GameMessages(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	p := g.AddPlayer(nil)
+
+	// Commands are queued, so tick after each one
+	dispatch := func(frame string) error {
+		err := network.Handlers.Dispatch(&network.Context{Player: p}, []byte(frame))
+		g.Update()
+		return err
+	}
+	if err := dispatch(`{"type":"AUTO_ATTACK","enabled":false}`); err != nil || p.AutoAttack {
+		t.Errorf("Expected AUTO_ATTACK to turn auto attack off, err %v", err)
+	}
+	if err := dispatch(`{"type":"EQUIP","item_id":-1000,"slot":9}`); err == nil {
+		t.Error("Expected an out of range slot to fail validation")
+	}
+	if err := dispatch(`{"type":"MARKET_LIST","item_id":-1000,"price":0}`); err == nil {
+		t.Error("Expected a zero price to fail validation")
+	}
+	if err := dispatch(`{"type":"EQUIP","item_id":-1000,"slot":0}`); err != nil || p.Equipment[0] == nil {
+		t.Errorf("Expected EQUIP to equip the item, err %v", err)
+	}
+
+	// Messages needing a game are dropped for players without one
+	loose := game.NewPlayer(99, nil, nil)
+	if err := network.Handlers.Dispatch(&network.Context{Player: loose}, []byte(`{"type":"PARTY_LEAVE"}`)); err != nil {
+		t.Errorf("Expected InGame to drop the message quietly, got %v", err)
+	}
+}
+
+func TestHandlers_DocsUpToDate(t *testing.T) {
+	want, err := os.ReadFile("../../docs/messages.md")
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	var got bytes.Buffer
+	network.Handlers.WriteMarkdown(&got)
+	if !bytes.Equal(got.Bytes(), want) {
+		t.Error("docs/messages.md is stale; run go generate ./internal/network")
+	}
+}
@@ -3,6 +3,7 @@ package network_test
 import (
 	"bufio"
 	"encoding/json"
+	"io"
 	"mmorpg/internal/game"
 	"mmorpg/internal/network"
 	"net"
@@ -14,8 +15,8 @@ func TestHandleConnection(t *testing.T) {
 	// Use net.Pipe to simulate a connection without opening a real port
 	serverConn, clientConn := net.Pipe()
 
-	g := game.NewGame()
-	s := network.NewServer(":0", g)
+	g := game.NewGame(game.DefaultConfig())
+	s := network.NewServer(":0", network.NewHub(g, network.DefaultSessionConfig()))
 
 	s.GetWG().Add(1)
 
@@ -33,6 +34,8 @@ func TestHandleConnection(t *testing.T) {
 		}
 	}()
 
+	clientConn.Write([]byte(`{"type":"HELLO","version":2}` + "\n"))
+
 	// Test 1: Send MOVE command (JSON)
 	moveCmd := game.MsgMove{Type: "MOVE", X: 10.5, Y: 20.0}
 	data, _ := json.Marshal(moveCmd)
@@ -48,6 +51,7 @@ func TestHandleConnection(t *testing.T) {
 	// So we can only verify if game state updated.
 
 	time.Sleep(100 * time.Millisecond) // Wait for processing
+	g.Update()                         // Commands are applied on the tick
 
 	// Verify directly on player state if we had access to the player instance.
 	// Since we don't easily get the player instance from here (it's inside server),
@@ -70,3 +74,96 @@ func TestHandleConnection(t *testing.T) {
 	// Clean up
 	clientConn.Close()
 }
+
+func TestServer_LinesAndStop(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	s := network.NewServer("127.0.0.1:0", network.NewHub(g, network.DefaultSessionConfig()))
+	go s.Start()
+
+	var addr net.Addr
+	for i := 0; i < 100 && addr == nil; i++ {
+		time.Sleep(10 * time.Millisecond)
+		addr = s.Addr()
+	}
+	if addr == nil {
+		t.Fatal("Server did not start listening")
+	}
+
+	conn, err := net.Dial("tcp", addr.String())
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer conn.Close()
+	conn.Write([]byte(`{"type":"HELLO","version":2}` + "\n"))
+
+	reader := bufio.NewReader(conn)
+	for i := 0; i < 3; i++ {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			t.Fatalf("ReadBytes: %v", err)
+		}
+		var msg map[string]interface{}
+		if err := json.Unmarshal(line, &msg); err != nil {
+			t.Fatalf("Expected one JSON message per line, got %q", line)
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected Stop to close open connections and return")
+	}
+	if len(g.GetPlayers()) != 0 {
+		t.Error("Expected the player to be removed after Stop")
+	}
+}
+
+func TestServer_FrameTooLarge(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	cfg := network.DefaultSessionConfig()
+	cfg.MaxFrameSize = 64
+	s := network.NewServer("127.0.0.1:0", network.NewHub(g, cfg))
+	go s.Start()
+	defer s.Stop()
+
+	var addr net.Addr
+	for i := 0; i < 100 && addr == nil; i++ {
+		time.Sleep(10 * time.Millisecond)
+		addr = s.Addr()
+	}
+	if addr == nil {
+		t.Fatal("Server did not start listening")
+	}
+
+	conn, err := net.Dial("tcp", addr.String())
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer conn.Close()
+	conn.Write([]byte(`{"type":"HELLO","version":2}` + "\n"))
+	for i := 0; i < 100 && len(g.GetPlayers()) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	big := make([]byte, 200)
+	for i := range big {
+		big[i] = 'x'
+	}
+	conn.Write(append(big, '\n'))
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	// The unread rest of the frame may turn the close into a reset
+	_, err = io.Copy(io.Discard, conn)
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		t.Fatal("Expected an oversized frame to close the connection")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if len(g.GetPlayers()) != 0 {
+		t.Error("Expected a kicked player to be removed without a grace period")
+	}
+}
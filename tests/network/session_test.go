@@ -0,0 +1,385 @@
+package network_test
+
+import (
+	"encoding/json"
+	"errors"
+	"mmorpg/internal/game"
+	"mmorpg/internal/network"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memCodec is an in-memory transport
+type memCodec struct {
+	in       chan []byte
+	mu       sync.Mutex
+	out      [][]byte
+	reason   string
+	closed   chan struct{}
+	once     sync.Once
+	deadline time.Time
+	pings    int
+	autoPong bool // answer pings like a live client
+	onPong   func()
+}
+
+var hello = []byte(`{"type":"HELLO","version":2,"codecs":["json"],"capabilities":["resume"]}`)
+
+// newMemCodec returns a codec whose client has already said HELLO
+func newMemCodec() *memCodec {
+	c := &memCodec{in: make(chan []byte, 8), closed: make(chan struct{})}
+	c.in <- hello
+	return c
+}
+
+type timeoutErr struct{}
+
+func (timeoutErr) Error() string   { return "timeout" }
+func (timeoutErr) Timeout() bool   { return true }
+func (timeoutErr) Temporary() bool { return true }
+
+func (c *memCodec) ReadFrame() ([]byte, error) {
+	for {
+		c.mu.Lock()
+		deadline := c.deadline
+		c.mu.Unlock()
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return nil, timeoutErr{}
+		}
+
+		// Poll so deadlines extended by pongs are picked up
+		select {
+		case b := <-c.in:
+			return b, nil
+		case <-c.closed:
+			return nil, errors.New("closed")
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+}
+
+func (c *memCodec) WriteFrame(b []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.out = append(c.out, b)
+	return nil
+}
+
+func (c *memCodec) Ping() error {
+	c.mu.Lock()
+	c.pings++
+	pong, onPong := c.autoPong, c.onPong
+	c.mu.Unlock()
+	if pong && onPong != nil {
+		onPong()
+	}
+	return nil
+}
+
+func (c *memCodec) SetPongHandler(fn func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onPong = fn
+}
+
+func (c *memCodec) SetWriteDeadline(time.Time) error { return nil }
+func (c *memCodec) SetReadLimit(int)                 {}
+
+func (c *memCodec) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deadline = t
+	return nil
+}
+
+func (c *memCodec) Close(reason string) error {
+	c.once.Do(func() {
+		c.mu.Lock()
+		c.reason = reason
+		c.mu.Unlock()
+		close(c.closed)
+	})
+	return nil
+}
+
+func (c *memCodec) RemoteAddr() string { return "mem" }
+func (c *memCodec) Transport() string  { return "mem" }
+
+func (c *memCodec) closeReason() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.reason
+}
+
+func serve(hub *network.Hub, c *memCodec) chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		hub.Serve(c)
+		close(done)
+	}()
+	return done
+}
+
+func waitDone(t *testing.T, done chan struct{}) {
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected session to end")
+	}
+}
+
+func TestHub_CustomTransport(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	hub := network.NewHub(g, network.DefaultSessionConfig())
+
+	c := newMemCodec()
+	done := serve(hub, c)
+	c.in <- []byte(`{"type":"AUTO_ATTACK","enabled":false}`)
+
+	time.Sleep(50 * time.Millisecond)
+	if len(g.GetPlayers()) != 1 {
+		t.Fatalf("Expected 1 player, got %d", len(g.GetPlayers()))
+	}
+	c.mu.Lock()
+	sent := len(c.out)
+	c.mu.Unlock()
+	if sent == 0 {
+		t.Error("Expected initial messages to be written through the codec")
+	}
+
+	if hub.Shutdown() != 1 {
+		t.Error("Expected Shutdown to close one session")
+	}
+	waitDone(t, done)
+	if c.closeReason() != network.CloseServerShutdown {
+		t.Errorf("Expected close reason %q, got %q", network.CloseServerShutdown, c.closeReason())
+	}
+	if len(g.GetPlayers()) != 0 {
+		t.Error("Expected player to be removed")
+	}
+}
+
+func TestHub_ReadTimeout(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	hub := network.NewHub(g, network.SessionConfig{
+		PingInterval: 10 * time.Millisecond,
+		ReadTimeout:  50 * time.Millisecond,
+	})
+
+	// Pings go unanswered, as from a dead connection
+	c := newMemCodec()
+	waitDone(t, serve(hub, c))
+	if c.closeReason() != network.CloseReadTimeout {
+		t.Errorf("Expected close reason %q, got %q", network.CloseReadTimeout, c.closeReason())
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.pings == 0 {
+		t.Error("Expected heartbeats to be sent")
+	}
+}
+
+func TestHub_PongsKeepAliveUntilAFK(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	hub := network.NewHub(g, network.SessionConfig{
+		PingInterval: 10 * time.Millisecond,
+		ReadTimeout:  30 * time.Millisecond,
+		AFKTimeout:   150 * time.Millisecond,
+	})
+
+	c := newMemCodec()
+	c.autoPong = true
+	start := time.Now()
+	waitDone(t, serve(hub, c))
+
+	if c.closeReason() != network.CloseAFK {
+		t.Errorf("Expected close reason %q, got %q", network.CloseAFK, c.closeReason())
+	}
+	if time.Since(start) < 150*time.Millisecond {
+		t.Error("Expected pongs to keep the session past the read timeout")
+	}
+}
+
+func TestHub_CommandsResetAFK(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	hub := network.NewHub(g, network.SessionConfig{
+		PingInterval: 10 * time.Millisecond,
+		AFKTimeout:   80 * time.Millisecond,
+	})
+
+	c := newMemCodec()
+	done := serve(hub, c)
+	for i := 0; i < 6; i++ {
+		time.Sleep(30 * time.Millisecond)
+		c.in <- []byte(`{"type":"AUTO_ATTACK","enabled":false}`)
+	}
+	if c.closeReason() != "" {
+		t.Fatalf("Expected active player to stay connected, closed with %q", c.closeReason())
+	}
+	waitDone(t, done)
+	if c.closeReason() != network.CloseAFK {
+		t.Errorf("Expected close reason %q, got %q", network.CloseAFK, c.closeReason())
+	}
+}
+
+func TestHub_AFKWithoutHeartbeats(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	hub := network.NewHub(g, network.SessionConfig{AFKTimeout: 50 * time.Millisecond})
+
+	c := newMemCodec()
+	waitDone(t, serve(hub, c))
+	if c.closeReason() != network.CloseAFK {
+		t.Errorf("Expected close reason %q, got %q", network.CloseAFK, c.closeReason())
+	}
+}
+
+func TestHub_SendQueueFull(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	// AddPlayer alone sends more than two messages
+	hub := network.NewHub(g, network.SessionConfig{SendQueue: 2})
+
+	c := newMemCodec()
+	c.mu.Lock() // stall the writer
+	done := serve(hub, c)
+	time.Sleep(50 * time.Millisecond)
+	c.mu.Unlock()
+
+	waitDone(t, done)
+	if c.closeReason() != network.CloseSendQueueFull {
+		t.Errorf("Expected close reason %q, got %q", network.CloseSendQueueFull, c.closeReason())
+	}
+}
+
+func TestHub_RejectsAfterShutdown(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	hub := network.NewHub(g, network.DefaultSessionConfig())
+	hub.Shutdown()
+
+	c := newMemCodec()
+	waitDone(t, serve(hub, c))
+	if len(g.GetPlayers()) != 0 {
+		t.Error("Expected no player to join after shutdown")
+	}
+}
+
+func TestHub_ResumeAfterDrop(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	hub := network.NewHub(g, network.DefaultSessionConfig())
+
+	c := newMemCodec()
+	done := serve(hub, c)
+	time.Sleep(20 * time.Millisecond)
+	c.Close(network.CloseClientDisconnect)
+	waitDone(t, done)
+
+	c.mu.Lock()
+	var token string
+	var id int
+	for _, b := range c.out {
+		var msg game.MsgWelcome
+		if json.Unmarshal(b, &msg) == nil && msg.Type == "WELCOME" {
+			token, id = msg.Token, msg.ID
+		}
+	}
+	c.mu.Unlock()
+	if len(g.GetPlayers()) != 1 {
+		t.Fatal("Expected the dropped player to be held")
+	}
+
+	c2 := newMemCodec()
+	done2 := serve(hub, c2)
+	c2.in <- []byte(`{"type":"RESUME","token":"` + token + `"}`)
+	time.Sleep(50 * time.Millisecond)
+
+	players := g.GetPlayers()
+	if len(players) != 1 || players[id] == nil {
+		t.Fatalf("Expected only player %d after resume, got %v", id, players)
+	}
+
+	hub.Shutdown()
+	waitDone(t, done2)
+	if len(g.GetPlayers()) != 0 {
+		t.Error("Expected shutdown to remove the player without a grace period")
+	}
+}
+
+func TestHub_RateLimitKickAndBan(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	cfg := network.DefaultSessionConfig()
+	cfg.RateLimits = map[string]network.RateLimit{"AUTO_ATTACK": {Rate: 1, Burst: 2}}
+	cfg.KickAfter = 3
+	cfg.BanAfter = 2
+	hub := network.NewHub(g, cfg)
+
+	for i := 0; i < 2; i++ {
+		c := newMemCodec()
+		done := serve(hub, c)
+		for j := 0; j < 5; j++ {
+			c.in <- []byte(`{"type":"AUTO_ATTACK","enabled":true}`)
+		}
+		waitDone(t, done)
+		if c.closeReason() != network.CloseRateLimited {
+			t.Fatalf("Expected close reason %q, got %q", network.CloseRateLimited, c.closeReason())
+		}
+	}
+
+	if !hub.Banned("mem") {
+		t.Fatal("Expected the host to be banned after repeated kicks")
+	}
+	c := newMemCodec()
+	waitDone(t, serve(hub, c))
+	if c.closeReason() != network.CloseBanned {
+		t.Errorf("Expected close reason %q, got %q", network.CloseBanned, c.closeReason())
+	}
+}
+
+func TestHub_FastMoveStreamNotKicked(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	cfg := network.DefaultSessionConfig()
+	// Use up the default MOVE burst in half a second instead of five
+	move := cfg.RateLimits["MOVE"]
+	move.Burst = 20
+	cfg.RateLimits["MOVE"] = move
+	hub := network.NewHub(g, cfg)
+
+	// A client on a 240 Hz screen sends one MOVE per frame
+	c := newMemCodec()
+	done := serve(hub, c)
+	ticker := time.NewTicker(time.Second / 240)
+	defer ticker.Stop()
+stream:
+	for i := 0; i < 480; i++ {
+		<-ticker.C
+		select {
+		case c.in <- []byte(`{"type":"MOVE","x":400,"y":300}`):
+		case <-done:
+			break stream
+		}
+	}
+	if c.closeReason() != "" {
+		t.Errorf("Expected extra MOVEs to be dropped without a kick, closed with %q", c.closeReason())
+	}
+	hub.Shutdown()
+	waitDone(t, done)
+}
+
+func TestHub_UnlimitedTypesPass(t *testing.T) {
+	g := game.NewGame(game.DefaultConfig())
+	cfg := network.DefaultSessionConfig()
+	cfg.RateLimits = map[string]network.RateLimit{"MOVE": {Rate: 1, Burst: 1}}
+	cfg.KickAfter = 3
+	hub := network.NewHub(g, cfg)
+
+	c := newMemCodec()
+	done := serve(hub, c)
+	for j := 0; j < 8; j++ {
+		c.in <- []byte(`{"type":"AUTO_ATTACK","enabled":true}`)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if c.closeReason() != "" {
+		t.Errorf("Expected types without a limit or default to pass, closed with %q", c.closeReason())
+	}
+	hub.Shutdown()
+	waitDone(t, done)
+}
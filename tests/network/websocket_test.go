@@ -0,0 +1,116 @@
+package network_test
+
+import (
+	"crypto/tls"
+	"mmorpg/internal/game"
+	"mmorpg/internal/network"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func newWSTestServer(t *testing.T, cfg network.WSConfig) *httptest.Server {
+	cfg.Path = "/ws"
+	g := game.NewGame(game.DefaultConfig())
+	hub := network.NewHub(g, network.DefaultSessionConfig())
+	srv := httptest.NewServer(network.NewWSServer(hub, cfg).Handler())
+	t.Cleanup(func() {
+		hub.Shutdown()
+		srv.Close()
+	})
+	return srv
+}
+
+func dialWS(url, origin string) (*websocket.Conn, *http.Response, error) {
+	h := http.Header{}
+	if origin != "" {
+		h.Set("Origin", origin)
+	}
+	return websocket.DefaultDialer.Dial(strings.Replace(url, "http", "ws", 1)+"/ws", h)
+}
+
+func TestWS_OriginAllowList(t *testing.T) {
+	srv := newWSTestServer(t, network.WSConfig{AllowedOrigins: []string{"https://game.example"}})
+
+	conn, _, err := dialWS(srv.URL, "https://game.example")
+	if err != nil {
+		t.Fatalf("Expected listed origin to connect: %v", err)
+	}
+	conn.Close()
+
+	if _, resp, err := dialWS(srv.URL, "https://evil.example"); err == nil || resp.StatusCode != http.StatusForbidden {
+		t.Error("Expected unlisted origin to be rejected")
+	}
+
+	conn, _, err = dialWS(srv.URL, "")
+	if err != nil {
+		t.Fatalf("Expected clients without an Origin header to connect: %v", err)
+	}
+	conn.Close()
+}
+
+func TestWS_SameOriginByDefault(t *testing.T) {
+	srv := newWSTestServer(t, network.WSConfig{})
+
+	conn, _, err := dialWS(srv.URL, srv.URL)
+	if err != nil {
+		t.Fatalf("Expected same origin to connect: %v", err)
+	}
+	conn.Close()
+
+	if _, _, err := dialWS(srv.URL, "https://evil.example"); err == nil {
+		t.Error("Expected cross origin to be rejected without an allow-list")
+	}
+}
+
+func TestWS_Compression(t *testing.T) {
+	srv := newWSTestServer(t, network.WSConfig{Compression: true})
+
+	dialer := websocket.Dialer{EnableCompression: true}
+	conn, resp, err := dialer.Dial(strings.Replace(srv.URL, "http", "ws", 1)+"/ws", nil)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer conn.Close()
+	conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"HELLO","version":2,"capabilities":["compression"]}`))
+
+	if !strings.Contains(resp.Header.Get("Sec-Websocket-Extensions"), "permessage-deflate") {
+		t.Error("Expected permessage-deflate to be negotiated")
+	}
+	var ok game.MsgHelloOK
+	if err := conn.ReadJSON(&ok); err != nil {
+		t.Fatalf("Expected compressed messages to be readable: %v", err)
+	}
+	if len(ok.Capabilities) != 1 || ok.Capabilities[0] != "compression" {
+		t.Errorf("Expected compression to be acknowledged, got %v", ok.Capabilities)
+	}
+}
+
+func TestWS_SelfSignedTLS(t *testing.T) {
+	tlsConfig, err := network.TLSConfig("", "", true, nil)
+	if err != nil || tlsConfig == nil {
+		t.Fatalf("TLSConfig: %v", err)
+	}
+
+	g := game.NewGame(game.DefaultConfig())
+	hub := network.NewHub(g, network.DefaultSessionConfig())
+	srv := httptest.NewUnstartedServer(network.NewWSServer(hub, network.WSConfig{Path: "/ws"}).Handler())
+	srv.TLS = tlsConfig
+	srv.StartTLS()
+	defer srv.Close()
+	defer hub.Shutdown()
+
+	dialer := websocket.Dialer{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	conn, _, err := dialer.Dial(strings.Replace(srv.URL, "https", "wss", 1)+"/ws", nil)
+	if err != nil {
+		t.Fatalf("Expected wss to work with the self-signed certificate: %v", err)
+	}
+	conn.Close()
+
+	if cfg, err := network.TLSConfig("", "", false, nil); err != nil || cfg != nil {
+		t.Error("Expected no TLS config when TLS is off")
+	}
+}